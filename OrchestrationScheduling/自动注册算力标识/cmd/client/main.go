@@ -1,44 +1,223 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"register-power-resources/pkg/client"
 )
 
+// serverOptions 为各子命令共用的 server 参数，-server 指定时不再读取配置文件
+type serverOptions struct {
+	configFile string
+	server     string
+	serverType string
+}
+
+func (o *serverOptions) addFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configFile, "config", client.DefaultConfigFile, "Server config file")
+	fs.StringVar(&o.server, "server", "", "Server URL, overrides the config file")
+	fs.StringVar(&o.serverType, "server-type", client.ServerTypeLocal, "Type of the -server: local or remote")
+}
+
+func (o *serverOptions) load() error {
+	if o.server != "" {
+		client.SetServers([]client.ServerConfig{{ServerURL: o.server, Type: o.serverType}})
+		return nil
+	}
+	return client.LoadConfigFile(o.configFile)
+}
+
+// stringsFlag 为可重复指定的参数
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func main() {
-	client.LoadConfig()
+	var servers serverOptions
+
+	registerCmd := flag.NewFlagSet("register", flag.ExitOnError)
+	registerFile := registerCmd.String("f", "-", "File with compute ids (JSON array, {\"compute_ids\": [...]} or one per line), - for stdin")
 
-	//registerCmd := flag.NewFlagSet("register", flag.ExitOnError)
-	//registerData := registerCmd.String("data", "", "Resource data string")
-	//
-	//unregisterCmd := flag.NewFlagSet("unregister", flag.ExitOnError)
-	//unregisterID := unregisterCmd.String("id", "", "Resource ID")
+	unregisterCmd := flag.NewFlagSet("unregister", flag.ExitOnError)
+	var unregisterIDs stringsFlag
+	unregisterCmd.Var(&unregisterIDs, "id", "Compute id to unregister, may be repeated")
+	unregisterPrefix := unregisterCmd.String("prefix", "", "Unregister all resources with this compute id prefix")
+	unregisterSelector := unregisterCmd.String("selector", "", "Unregister all resources matching the selector, e.g. city=1101,enterprise=20001")
+	unregisterDryRun := unregisterCmd.Bool("dry-run", false, "Only print the resources a prefix or selector matches")
 
 	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
 	getID := getCmd.String("id", "", "Resource ID")
+	getOutput := getCmd.String("o", client.OutputTable, "Output format: table, wide, json or yaml")
+
+	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+	var listOptions client.ListOptions
+	listCmd.StringVar(&listOptions.Prefix, "prefix", "", "Compute id prefix")
+	listCmd.StringVar(&listOptions.Selector, "selector", "", "Field selector, e.g. city=1101,enterprise=20001,chip-type=gpu")
+	listCmd.IntVar(&listOptions.Offset, "offset", 0, "Number of matching resources to skip")
+	listCmd.IntVar(&listOptions.Limit, "limit", 0, "Maximum resources per server, 0 for all")
+	listOutput := listCmd.String("o", client.OutputTable, "Output format: table, wide, json or yaml")
+
+	exportCmd := flag.NewFlagSet("export", flag.ExitOnError)
+	exportFormat := exportCmd.String("format", "jsonl", "Snapshot format: jsonl or csv")
+	exportFile := exportCmd.String("file", "-", "Output file, - for stdout")
+
+	importCmd := flag.NewFlagSet("import", flag.ExitOnError)
+	importFormat := importCmd.String("format", "jsonl", "Snapshot format: jsonl or csv")
+	importFile := importCmd.String("file", "-", "Input file, - for stdin")
+	importMode := importCmd.String("mode", "merge", "Import mode: merge or replace")
+
+	for _, fs := range []*flag.FlagSet{registerCmd, unregisterCmd, getCmd, listCmd, exportCmd, importCmd} {
+		servers.addFlags(fs)
+	}
 
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: client <command> [<args>]")
-		fmt.Println("Commands: register, unregister, get, list")
+		fmt.Println("Commands: register, unregister, get, list, export, import")
 		return
 	}
 
 	switch os.Args[1] {
-	//case "register":
-	//	registerCmd.Parse(os.Args[2:])
-	//	client.RegisterResource(*registerData)
-	//case "unregister":
-	//	unregisterCmd.Parse(os.Args[2:])
-	//	client.UnregisterResource(*unregisterID)
+	case "register":
+		registerCmd.Parse(os.Args[2:])
+		mustLoad(&servers)
+		var in io.Reader = os.Stdin
+		if *registerFile != "-" {
+			file, err := os.Open(*registerFile)
+			if err != nil {
+				fail("Error opening compute id file:", err)
+			}
+			defer file.Close()
+			in = file
+		}
+		ids, err := client.ReadComputeIDs(in)
+		if err != nil {
+			fail(err)
+		}
+		if err := client.RegisterResource(ids).Err(); err != nil {
+			fail(err)
+		}
+	case "unregister":
+		unregisterCmd.Parse(os.Args[2:])
+		mustLoad(&servers)
+		unregisterIDs = append(unregisterIDs, unregisterCmd.Args()...)
+		options := client.ListOptions{Prefix: *unregisterPrefix, Selector: *unregisterSelector}
+		switch {
+		case len(unregisterIDs) > 0 && (options.Prefix != "" || options.Selector != ""):
+			fail("-id cannot be combined with -prefix or -selector")
+		case len(unregisterIDs) > 0:
+			if err := client.UnregisterResources(unregisterIDs).Err(); err != nil {
+				fail(err)
+			}
+		case options.Prefix == "" && options.Selector == "":
+			fail("one of -id, -prefix or -selector is required")
+		case *unregisterDryRun:
+			if err := client.PrintResources(os.Stdout, client.OutputTable, client.ListResources(options)); err != nil {
+				fail(err)
+			}
+		default:
+			if err := client.UnregisterMatching(options).Err(); err != nil {
+				fail(err)
+			}
+		}
 	case "get":
 		getCmd.Parse(os.Args[2:])
-		client.GetResource(*getID)
+		mustLoad(&servers)
+		if *getID == "" && getCmd.NArg() > 0 {
+			*getID = getCmd.Arg(0)
+		}
+		if *getID == "" {
+			fail("-id is required")
+		}
+		checkOutput(*getOutput)
+		results := client.GetResource(*getID)
+		found := false
+		for _, result := range results {
+			if len(result.Items) > 0 {
+				found = true
+			} else if errors.Is(result.Err, client.ErrNotFound) {
+				fmt.Fprintf(os.Stderr, "Resource not found on %s\n", result.ServerURL)
+			}
+		}
+		if !found {
+			os.Exit(1)
+		}
+		if err := client.PrintResources(os.Stdout, *getOutput, results); err != nil {
+			fail(err)
+		}
 	case "list":
-		client.ListResources()
+		listCmd.Parse(os.Args[2:])
+		mustLoad(&servers)
+		checkOutput(*listOutput)
+		results := client.ListResources(listOptions)
+		if err := client.PrintResources(os.Stdout, *listOutput, results); err != nil {
+			fail(err)
+		}
+		for _, result := range results {
+			if result.Err != nil {
+				os.Exit(1)
+			}
+		}
+	case "export":
+		exportCmd.Parse(os.Args[2:])
+		mustLoad(&servers)
+		var out io.Writer = os.Stdout
+		if *exportFile != "-" {
+			file, err := os.Create(*exportFile)
+			if err != nil {
+				fail("Error creating snapshot file:", err)
+			}
+			defer file.Close()
+			out = file
+		}
+		if err := client.ExportSnapshot(*exportFormat, out); err != nil {
+			fail(err)
+		}
+	case "import":
+		importCmd.Parse(os.Args[2:])
+		mustLoad(&servers)
+		var in io.Reader = os.Stdin
+		if *importFile != "-" {
+			file, err := os.Open(*importFile)
+			if err != nil {
+				fail("Error opening snapshot file:", err)
+			}
+			defer file.Close()
+			in = file
+		}
+		if err := client.ImportSnapshot(in, *importFormat, *importMode); err != nil {
+			fail(err)
+		}
 	default:
 		fmt.Println("Unknown command:", os.Args[1])
+		os.Exit(2)
 	}
 }
+
+func mustLoad(servers *serverOptions) {
+	if err := servers.load(); err != nil {
+		fail(err)
+	}
+}
+
+func checkOutput(format string) {
+	if err := client.ValidateOutputFormat(format); err != nil {
+		fail(err)
+	}
+}
+
+func fail(v ...interface{}) {
+	fmt.Fprintln(os.Stderr, v...)
+	os.Exit(1)
+}
@@ -1,24 +1,41 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"register-power-resources/pkg/client"
 	"register-power-resources/pkg/controller"
+	"syscall"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 func main() {
-	fmt.Printf("[INfo]len(os.Args): %d\n", len(os.Args))
-	fmt.Printf("[INfo]os.Args[0]: %s\n", os.Args[0])
-	fmt.Printf("[INfo]os.Args[1]: %s\n", os.Args[1])
+	options := controller.NewOptions()
+	options.AddFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: controller [flags] <register|print>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: controller <args>")
-		fmt.Println("Args: register, print")
-		return
+	switch flag.Arg(0) {
+	case "register":
+		options.Register = true
+	case "print":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := options.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	client.SetRetryPolicy(options.Retry)
 
 	// 获取当前集群的配置
 	config, err := rest.InClusterConfig()
@@ -32,14 +49,52 @@ func main() {
 		panic(err.Error())
 	}
 
-	// 创建并启动 LabelWatcher
+	// 收到 SIGTERM 时先停止全部集群控制器，再释放 Lease，避免与新的 leader 同时上报
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// 创建并启动 LabelWatcher，提供 label-config 中的静态字段名称
 	labelWatcher := controller.NewLabelWatcher(clientset)
-	go labelWatcher.Run()
+	go labelWatcher.Run(ctx)
+	manager := controller.NewClusterManager(options, labelWatcher)
+	go func() {
+		<-signalCtx.Done()
+		manager.Stop()
+		cancel()
+	}()
+
+	// KubeconfigWatcher 监听成员集群的 kubeconfig ConfigMap 及 Secret，启用选主时只在 leader 上运行
+	kubeconfigWatcher := controller.NewKubeconfigWatcher(clientset, manager)
+	runClusters := func(ctx context.Context) {
+		kubeconfigWatcher.Run(ctx)
+		manager.Stop()
+	}
 
-	// 创建并启动 ConfigMapWatcher
-	configMapWatcher := controller.NewConfigMapWatcher(clientset, labelWatcher)
-	go configMapWatcher.Run()
+	var leader *controller.LeaderElection
+	if options.LeaderElect {
+		if leader, err = controller.NewLeaderElection(clientset, options, runClusters); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	if options.MetricsAddr != "" {
+		go func() {
+			if err := controller.ServeMetrics(options.MetricsAddr, manager, leader); err != nil {
+				fmt.Printf("Error serving metrics: %v\n", err)
+			}
+		}()
+	}
 
-	// 阻止主 goroutine 退出
-	select {}
+	if leader == nil {
+		runClusters(ctx)
+		return
+	}
+	leader.Run(ctx)
+	// 失去领导权后集群控制器已停止，退出后由 Deployment 重启并重新参与选主
+	if signalCtx.Err() == nil {
+		fmt.Println("Leader election lost, exiting")
+		os.Exit(1)
+	}
 }
@@ -1,21 +1,79 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"github.com/gorilla/mux"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"register-power-resources/pkg/server"
+	"syscall"
+
+	"github.com/gorilla/mux"
 )
 
 func main() {
+	options := server.NewOptions()
+	options.AddFlags(flag.CommandLine)
+	flag.Parse()
+	// 先校验参数及环境变量，再创建审计日志等
+	if err := options.Validate(); err != nil {
+		fmt.Printf("Invalid options: %v\n", err)
+		os.Exit(1)
+	}
+
 	router := mux.NewRouter()
-	router.HandleFunc("/resources", server.RegisterResource).Methods("POST")
-	router.HandleFunc("/resources/{id}", server.UnregisterResource).Methods("DELETE")
+	router.Handle("/resources", server.RateLimit(http.HandlerFunc(server.RegisterResource))).Methods("POST")
+	router.Handle("/resources/{id}", server.RateLimit(http.HandlerFunc(server.UnregisterResource))).Methods("DELETE")
 	router.HandleFunc("/resources/{id}", server.GetResource).Methods("GET")
 	router.HandleFunc("/resources", server.ListResources).Methods("GET")
+	router.Handle("/v1/resources/unregister", server.RateLimit(http.HandlerFunc(server.UnregisterResources))).Methods("POST")
+	router.HandleFunc("/v1/resources", server.ListResourcesJSON).Methods("GET")
+	router.HandleFunc("/v1/resources/{id}", server.GetResourceJSON).Methods("GET")
+	router.HandleFunc("/v1/stats", server.Stats).Methods("GET")
+	router.Handle("/v1/watch", server.NewWatchHandler(options)).Methods("GET")
+	router.HandleFunc("/metrics", server.Metrics).Methods("GET")
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}).Methods("GET")
+	router.HandleFunc("/v1/snapshot", server.ExportSnapshot).Methods("GET")
+	router.Handle("/v1/snapshot", server.RateLimit(http.HandlerFunc(server.ImportSnapshot))).Methods("POST")
+	router.HandleFunc("/v1/audit", server.QueryAudit).Methods("GET")
+	router.HandleFunc("/v1/match", server.MatchResourcesHandler).Methods("POST")
+	router.HandleFunc("/v1/templates", server.CreateTaskTemplate).Methods("POST")
+	router.HandleFunc("/v1/templates/{id}", server.GetTaskTemplate).Methods("GET")
+	router.HandleFunc("/v1/templates/{id}/matches", server.MatchTaskTemplate).Methods("GET")
+	router.HandleFunc("/v1/templates/{id}/paths", server.CreateTaskPath).Methods("POST")
+	router.HandleFunc("/v1/templates/{id}/paths", server.ListTaskPaths).Methods("GET")
+
+	// 收到 SIGTERM/SIGINT 后停止接收新请求，并等待处理中的请求完成
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if options.AuditLogFile != "" {
+		if err := server.EnableAuditLog(options.AuditLogFile, int64(options.AuditLogMaxSizeMB)<<20,
+			options.AuditLogMaxBackups); err != nil {
+			fmt.Printf("Error enabling audit log: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if options.ClientsConfigFile != "" {
+		config, err := server.LoadClientsConfig(options.ClientsConfigFile)
+		if err != nil {
+			fmt.Printf("Error loading clients config: %v\n", err)
+			os.Exit(1)
+		}
+		server.EnableClientLimits(config)
+		if options.TenantIsolation {
+			server.EnableTenancy(config)
+		}
+	}
+	server.StartExpiry(ctx, options.ResourceTTL)
 
-	fmt.Println("Starting server at :8080")
-	http.ListenAndServe(":8080", router)
-	log.Fatal(http.ListenAndServe(":8080", router))
+	if err := server.Run(ctx, options, router); err != nil {
+		fmt.Printf("Server exited with error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Server stopped")
 }
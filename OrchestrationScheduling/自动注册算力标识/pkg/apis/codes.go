@@ -0,0 +1,107 @@
+package apis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 芯片类型编码，见算力标识体系附录 2.9
+const (
+	ChipTypeGPU   = "00000"
+	ChipTypeCPU   = "00001"
+	ChipTypeFPGA  = "00010"
+	ChipTypeASIC  = "00011"
+	ChipTypeNPU   = "00100"
+	ChipTypeOther = "00111"
+)
+
+var ChipTypeNames = map[string]string{
+	"gpu":   ChipTypeGPU,
+	"cpu":   ChipTypeCPU,
+	"fpga":  ChipTypeFPGA,
+	"asic":  ChipTypeASIC,
+	"npu":   ChipTypeNPU,
+	"other": ChipTypeOther,
+}
+
+// ChipClass 为节点上的一类芯片，节点标签为 cncos.org/<Name>-chip-type、-chip-model、-chip-number、
+// -compute-capacity 及 -power-consumption
+type ChipClass struct {
+	Name string
+	// ChipType 为节点未设置 cncos.org/<Name>-chip-type 标签时的芯片类型编码
+	ChipType string
+}
+
+// ChipClasses 为组装算力标识时依次读取的芯片类别。DPU 在附录 2.9 中没有单独的编码，记为其他
+var ChipClasses = []ChipClass{
+	{"cpu", ChipTypeCPU},
+	{"gpu", ChipTypeGPU},
+	{"npu", ChipTypeNPU},
+	{"fpga", ChipTypeFPGA},
+	{"asic", ChipTypeASIC},
+	{"dpu", ChipTypeOther},
+}
+
+// ChipLabels 为一类芯片的节点标签
+type ChipLabels struct {
+	Type, Model, Number, ComputeCapacity, PowerConsumption string
+}
+
+// Labels 返回该类芯片的节点标签，如 npu 的芯片数量为 cncos.org/npu-chip-number
+func (c ChipClass) Labels() ChipLabels {
+	prefix := "cncos.org/" + c.Name + "-"
+	return ChipLabels{
+		Type:             prefix + "chip-type",
+		Model:            prefix + "chip-model",
+		Number:           prefix + "chip-number",
+		ComputeCapacity:  prefix + "compute-capacity",
+		PowerConsumption: prefix + "power-consumption",
+	}
+}
+
+// 网络类型编码，见算力标识体系附录 2.7
+const (
+	NetworkTypeEthernet = "00"
+	NetworkTypeIB       = "01"
+	NetworkTypeRoCE     = "10"
+	NetworkTypeOther    = "11"
+)
+
+var NetworkTypeNames = map[string]string{
+	"ethernet": NetworkTypeEthernet,
+	"ib":       NetworkTypeIB,
+	"roce":     NetworkTypeRoCE,
+	"other":    NetworkTypeOther,
+}
+
+// ParseChipType 接受芯片类型名称（如 gpu、npu）或 5 位编码
+func ParseChipType(s string) (string, error) {
+	return parseCode(s, ChipTypeNames, "chip type")
+}
+
+// ParseNetworkType 接受网络类型名称（如 ib、roce）或 2 位编码
+func ParseNetworkType(s string) (string, error) {
+	return parseCode(s, NetworkTypeNames, "network type")
+}
+
+// CodeName 返回编码对应的名称，未知编码原样返回
+func CodeName(names map[string]string, code string) string {
+	for name, c := range names {
+		if c == code {
+			return name
+		}
+	}
+	return code
+}
+
+func parseCode(s string, names map[string]string, kind string) (string, error) {
+	if code, ok := names[strings.ToLower(s)]; ok {
+		return code, nil
+	}
+	for _, code := range names {
+		if code == s {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s %q", kind, s)
+}
@@ -0,0 +1,686 @@
+package apis
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 算力标识静态字段的名称与编码对照，见算力标识体系附录 2.1 至 2.6（docs/powerresource.md）
+
+// CityCodes 为城市名称到 4 位编码的对照
+var CityCodes = map[string]string{
+	"北京":   "1101",
+	"天津":   "1201",
+	"石家庄":  "1301",
+	"唐山":   "1302",
+	"秦皇岛":  "1303",
+	"邯郸":   "1304",
+	"邢台":   "1305",
+	"保定":   "1306",
+	"张家口":  "1307",
+	"承德":   "1308",
+	"沧州":   "1309",
+	"廊坊":   "1310",
+	"衡水":   "1311",
+	"太原":   "1401",
+	"大同":   "1402",
+	"阳泉":   "1403",
+	"长治":   "1404",
+	"晋城":   "1405",
+	"朔州":   "1406",
+	"晋中":   "1407",
+	"运城":   "1408",
+	"忻州":   "1409",
+	"临汾":   "1410",
+	"吕梁":   "1411",
+	"呼和浩特": "1501",
+	"包头":   "1502",
+	"乌海":   "1503",
+	"赤峰":   "1504",
+	"通辽":   "1505",
+	"鄂尔多斯": "1506",
+	"呼伦贝尔": "1507",
+	"巴彦淖尔": "1508",
+	"乌兰察布": "1509",
+	"兴安盟":  "1522",
+	"锡林郭勒": "1525",
+	"阿拉善盟": "1529",
+	"沈阳":   "2101",
+	"大连":   "2102",
+	"鞍山":   "2103",
+	"抚顺":   "2104",
+	"本溪":   "2105",
+	"丹东":   "2106",
+	"锦州":   "2107",
+	"营口":   "2108",
+	"阜新":   "2109",
+	"辽阳":   "2110",
+	"盘锦":   "2111",
+	"铁岭":   "2112",
+	"朝阳":   "2113",
+	"葫芦岛":  "2114",
+	"长春":   "2201",
+	"吉林":   "2202",
+	"四平":   "2203",
+	"辽源":   "2204",
+	"通化":   "2205",
+	"白山":   "2206",
+	"松原":   "2207",
+	"白城":   "2208",
+	"延边":   "2224",
+	"哈尔滨":  "2301",
+	"齐齐哈尔": "2302",
+	"鸡西":   "2303",
+	"鹤岗":   "2304",
+	"双鸭山":  "2305",
+	"大庆":   "2306",
+	"伊春":   "2307",
+	"佳木斯":  "2308",
+	"七台河":  "2309",
+	"牡丹江":  "2310",
+	"黑河":   "2311",
+	"绥化":   "2312",
+	"大兴安岭": "2327",
+	"上海":   "3101",
+	"南京":   "3201",
+	"无锡":   "3202",
+	"徐州":   "3203",
+	"常州":   "3204",
+	"苏州":   "3205",
+	"南通":   "3206",
+	"连云港":  "3207",
+	"淮安":   "3208",
+	"盐城":   "3209",
+	"扬州":   "3210",
+	"镇江":   "3211",
+	"泰州":   "3212",
+	"宿迁":   "3213",
+	"杭州":   "3301",
+	"宁波":   "3302",
+	"温州":   "3303",
+	"嘉兴":   "3304",
+	"湖州":   "3305",
+	"绍兴":   "3306",
+	"金华":   "3307",
+	"衢州":   "3308",
+	"舟山":   "3309",
+	"台州":   "3310",
+	"丽水":   "3311",
+	"合肥":   "3401",
+	"芜湖":   "3402",
+	"蚌埠":   "3403",
+	"淮南":   "3404",
+	"马鞍山":  "3405",
+	"淮北":   "3406",
+	"铜陵":   "3407",
+	"安庆":   "3408",
+	"黄山":   "3410",
+	"滁州":   "3411",
+	"阜阳":   "3412",
+	"宿州":   "3413",
+	"六安":   "3415",
+	"豪州":   "3416",
+	"池州":   "3417",
+	"宣城":   "3418",
+	"福州":   "3501",
+	"厦门":   "3502",
+	"莆田":   "3503",
+	"三明":   "3504",
+	"泉州":   "3505",
+	"漳州":   "3506",
+	"南平":   "3507",
+	"龙岩":   "3508",
+	"宁德":   "3509",
+	"南昌":   "3601",
+	"景德镇":  "3602",
+	"萍乡":   "3603",
+	"九江":   "3604",
+	"新余":   "3605",
+	"鹰潭":   "3606",
+	"赣州":   "3607",
+	"吉安":   "3608",
+	"宜春":   "3609",
+	"抚州":   "3610",
+	"上饶":   "3611",
+	"济南":   "3701",
+	"青岛":   "3702",
+	"淄博":   "3703",
+	"枣庄":   "3704",
+	"东营":   "3705",
+	"烟台":   "3706",
+	"潍坊":   "3707",
+	"济宁":   "3708",
+	"泰安":   "3709",
+	"威海":   "3710",
+	"日照":   "3711",
+	"临沂":   "3713",
+	"德州":   "3714",
+	"聊城":   "3715",
+	"滨州":   "3716",
+	"菏泽":   "3717",
+	"郑州":   "4101",
+	"开封":   "4102",
+	"洛阳":   "4103",
+	"平顶山":  "4104",
+	"安阳":   "4105",
+	"鹤壁":   "4106",
+	"新乡":   "4107",
+	"焦作":   "4108",
+	"濮阳":   "4109",
+	"许昌":   "4110",
+	"漯河":   "4111",
+	"三门峡":  "4112",
+	"南阳":   "4113",
+	"商丘":   "4114",
+	"信阳":   "4115",
+	"周口":   "4116",
+	"驻马店":  "4117",
+	"武汉":   "4201",
+	"黄石":   "4202",
+	"十堰":   "4203",
+	"宜昌":   "4205",
+	"襄阳":   "4206",
+	"鄂州":   "4207",
+	"荆门":   "4208",
+	"孝感":   "4209",
+	"荆州":   "4210",
+	"黄冈":   "4211",
+	"咸宁":   "4212",
+	"随州":   "4213",
+	"恩施":   "4228",
+	"长沙":   "4301",
+	"株洲":   "4302",
+	"湘潭":   "4303",
+	"衡阳":   "4304",
+	"邵阳":   "4305",
+	"岳阳":   "4306",
+	"常德":   "4307",
+	"张家界":  "4308",
+	"益阳":   "4309",
+	"郴州":   "4310",
+	"永州":   "4311",
+	"怀化":   "4312",
+	"娄底":   "4313",
+	"湘西":   "4331",
+	"广州":   "4401",
+	"韶关":   "4402",
+	"深圳":   "4403",
+	"珠海":   "4404",
+	"汕头":   "4405",
+	"佛山":   "4406",
+	"江门":   "4407",
+	"湛江":   "4408",
+	"茂名":   "4409",
+	"肇庆":   "4412",
+	"惠州":   "4413",
+	"梅州":   "4414",
+	"汕尾":   "4415",
+	"河源":   "4416",
+	"阳江":   "4417",
+	"清远":   "4418",
+	"东莞":   "4419",
+	"中山":   "4420",
+	"潮州":   "4451",
+	"揭阳":   "4452",
+	"云浮":   "4453",
+	"南宁":   "4501",
+	"柳州":   "4502",
+	"桂林":   "4503",
+	"梧州":   "4504",
+	"北海":   "4505",
+	"防城港":  "4506",
+	"钦州":   "4507",
+	"贵港":   "4508",
+	"玉林":   "4509",
+	"白色":   "4510",
+	"贺州":   "4511",
+	"河池":   "4512",
+	"来宾":   "4513",
+	"崇左":   "4514",
+	"海口":   "4601",
+	"三亚":   "4602",
+	"三沙":   "4603",
+	"儋州":   "4604",
+	"重庆市区": "5001",
+	"重庆县":  "5002",
+	"成都":   "5101",
+	"自贡":   "5103",
+	"攀枝花":  "5104",
+	"泸州":   "5105",
+	"德阳":   "5106",
+	"绵阳":   "5107",
+	"广元":   "5108",
+	"遂宁":   "5109",
+	"内江":   "5110",
+	"乐山":   "5111",
+	"南充":   "5113",
+	"眉山":   "5114",
+	"宜宾":   "5115",
+	"广安":   "5116",
+	"达州":   "5117",
+	"雅安":   "5118",
+	"巴中":   "5119",
+	"资阳":   "5120",
+	"阿坝":   "5132",
+	"甘孜":   "5133",
+	"凉山":   "5134",
+	"贵阳":   "5201",
+	"六盘水":  "5202",
+	"遵义":   "5203",
+	"安顺":   "5204",
+	"毕节":   "5205",
+	"铜仁":   "5206",
+	"黔西南":  "5233",
+	"黔东南":  "5226",
+	"黔南":   "5227",
+	"昆明":   "5301",
+	"曲靖":   "5303",
+	"玉溪":   "5304",
+	"保山":   "5305",
+	"昭通":   "5306",
+	"丽江":   "5307",
+	"普洱":   "5308",
+	"临沧":   "5309",
+	"楚雄":   "5323",
+	"红河":   "5325",
+	"文山":   "5326",
+	"西双版纳": "5328",
+	"大理":   "5329",
+	"德宏":   "5331",
+	"怒江":   "5333",
+	"迪庆":   "5334",
+	"拉萨":   "5401",
+	"日喀则":  "5402",
+	"昌都":   "5403",
+	"林芝":   "5404",
+	"山南":   "5405",
+	"那曲":   "5406",
+	"阿里":   "5425",
+	"西安":   "6101",
+	"铜川":   "6102",
+	"宝鸡":   "6103",
+	"咸阳":   "6104",
+	"渭南":   "6105",
+	"延安":   "6106",
+	"汉中":   "6107",
+	"榆林":   "6108",
+	"安康":   "6109",
+	"南洛":   "6110",
+	"兰州":   "6201",
+	"嘉峪关":  "6202",
+	"金昌":   "6203",
+	"白银":   "6204",
+	"天水":   "6205",
+	"武威":   "6206",
+	"张掖":   "6207",
+	"平凉":   "6208",
+	"酒泉":   "6209",
+	"庆阳":   "6210",
+	"定西":   "6211",
+	"陇南":   "6212",
+	"临夏":   "6229",
+	"甘南":   "6230",
+	"西宁":   "6301",
+	"海东":   "6302",
+	"海北":   "6322",
+	"黄南":   "6323",
+	"海南":   "6325",
+	"果洛":   "6326",
+	"玉树":   "6327",
+	"海西":   "6328",
+	"银川":   "6401",
+	"石嘴山":  "6402",
+	"吴忠":   "6403",
+	"固原":   "6404",
+	"中卫":   "6405",
+	"乌鲁木齐": "6501",
+	"克拉玛依": "6502",
+	"吐鲁番":  "6504",
+	"哈密":   "6505",
+	"昌吉":   "6523",
+	"博尔塔拉": "6527",
+	"巴音郭楞": "6528",
+	"阿克苏":  "6529",
+	"克孜勒苏": "6530",
+	"喀什":   "6531",
+	"和田":   "6532",
+	"伊犁":   "6540",
+	"塔城":   "6542",
+	"阿勒泰":  "6543",
+	"其他":   "6544",
+}
+
+// IndustryCodes 为行业名称到 2 位编码的对照，旅游业与运输业编码相同
+var IndustryCodes = map[string]string{
+	"保险业":      "in",
+	"采矿":       "mn",
+	"能源":       "en",
+	"餐饮":       "fr",
+	"宾馆":       "ho",
+	"电讯业":      "tc",
+	"房地产":      "rs",
+	"服务":       "sv",
+	"服装业":      "cl",
+	"公益组织":     "no",
+	"广告业":      "ad",
+	"航空航天":     "av",
+	"化学":       "ch",
+	"健康、保健":    "hp",
+	"建筑业":      "bd",
+	"教育、培训":    "ed",
+	"计算机":      "cp",
+	"金属冶炼":     "mm",
+	"警察、消防":    "sf",
+	"会计":       "ac",
+	"美容":       "bt",
+	"媒体、出版":    "mp",
+	"木材、造纸":    "wp",
+	"零售、批发":    "rt",
+	"农业":       "ag",
+	"旅游业":      "tr",
+	"司法、律师":    "lw",
+	"司机":       "dr",
+	"体育运动":     "sp",
+	"学术研究":     "re",
+	"演艺、艺术、设计": "ar",
+	"银行、金融":    "bf",
+	"因特网":      "it",
+	"音乐舞蹈":     "md",
+	"邮政快递":     "sl",
+	"运输业":      "tr",
+	"政府机关":     "go",
+	"机械制造":     "mg",
+	"咨询":       "cn",
+	"其他":       "ot",
+}
+
+// EnterpriseCodes 为企业名称到 5 位编码的对照
+var EnterpriseCodes = map[string]string{
+	"天翼云科技有限公司":              "20001",
+	"中科院计算机网络信息中心":           "20002",
+	"中国移动云能力中心":              "20003",
+	"曙光智算信息技术有限公司":           "20004",
+	"西安未来人工智能计算中心":           "20006",
+	"北京昇腾创新人工智能科技中心有限公司":     "20007",
+	"鹏博士电信传媒集团":              "20008",
+	"中国电信集团有限公司":             "20009",
+	"中国电信股份有限公司宁夏分公司":        "20010",
+	"中国移动通信集团内蒙古有限公司":        "20011",
+	"中国联合网络通信有限公司宁夏回族自治区分公司": "20012",
+	"中联云港数据科技股份有限公司":         "20013",
+	"北京并行科技股份有限公司":           "20014",
+	"北京世纪互联宽带数据中心有限公司":       "20015",
+	"北京青云科技股份有限公司":           "20016",
+	"联通数字科技有限公司":             "20017",
+	"中国电信股份有限公司甘肃分公司":        "20018",
+	"光环云数据有限公司":              "20019",
+	"北京首都在线科技股份有限公司":         "20020",
+	"阿里云计算有限公司":              "20021",
+	"北京百度网讯科技有限公司":           "20022",
+	"中国移动通信集团北京有限公司":         "20023",
+	"京东科技信息技术有限公司":           "20024",
+	"北京红山信息科技研究院有限公司":        "20025",
+	"企商在线（北京）数据技术股份有限公司":     "20026",
+	"北京神州数码云科信息技术有限公司":       "20027",
+	"北京金山云网络技术有限公司":          "20028",
+	"宁夏西云算力科技有限公司":           "20029",
+	"天津智算数字产业发展有限公司":         "20030",
+	"中移建设有限公司北京分公司":          "20031",
+	"国家超级计算天津中心":             "20032",
+	"算力互联（北京）科技有限公司":         "20033",
+	"苏州国科综合数据中心有限公司":         "20034",
+	"亚信数字科技（南京）有限公司":         "20035",
+	"中电万维信息技术有限责任公司":         "20036",
+	"北京算能科技有限公司":             "20037",
+	"智算云（重庆）科技有限公司":          "20038",
+	"重庆骋风而来数字科技有限公司":         "20039",
+	"重庆中科云从科技有限公司":           "20040",
+	"其他":                     "20999",
+}
+
+// ResourceTypeCodes 为资源类型名称到 3 位编码的对照
+var ResourceTypeCodes = map[string]string{
+	"超算":   "401",
+	"智算":   "402",
+	"通用计算": "403",
+}
+
+// DataCenterCodes 为数据中心（可用区）名称到 3 位编码的对照
+var DataCenterCodes = map[string]string{
+	"可用区一": "501",
+	"可用区二": "502",
+	"可用区三": "503",
+	"可用区四": "504",
+	"可用区五": "505",
+	"可用区六": "506",
+	"可用区七": "507",
+	"可用区八": "508",
+	"可用区九": "509",
+	"可用区十": "510",
+	"可用区":  "500",
+}
+
+// ServiceTypeCodes 为服务类型名称到 6 位编码的对照
+var ServiceTypeCodes = map[string]string{
+	"云服务器":            "601001",
+	"轻量应用服务器":         "601002",
+	"裸金属云服务器":         "601003",
+	"GPU 云服务器":        "601004",
+	"FPGA 云服务器":       "601005",
+	"专用宿主机":           "601006",
+	"弹性伸缩":            "601007",
+	"高性能计算集群":         "601008",
+	"超级计算集群":          "601009",
+	"批量计算":            "601010",
+	"操作系统与工具":         "601011",
+	"计算加速套件":          "601012",
+	"分布式云":            "601013",
+	"本地专用集群":          "601014",
+	"专属计算集群":          "601015",
+	"边缘计算集群":          "601016",
+	"容器服务":            "602001",
+	"容器镜像服务":          "602002",
+	"Serverless":      "602003",
+	"云函数":             "602004",
+	"边缘容器服务":          "602005",
+	"云原生可观测":          "602006",
+	"云拨测":             "602007",
+	"混沌演练平台":          "602008",
+	"微服务引擎":           "602009",
+	"API 网关":          "602010",
+	"服务网格":            "602011",
+	"对象存储":            "603001",
+	"云硬盘":             "603002",
+	"文件存储":            "603003",
+	"分布式存储":           "603004",
+	"大数据存储":           "603005",
+	"关系型数据库":          "604001",
+	"云原生数据库":          "604002",
+	"云数据库 MySQL":      "604003",
+	"云数据库 MariaDB":    "604004",
+	"云数据库 SQL Server": "604005",
+	"云数据库 PostgreSQL": "604006",
+	"NoSQL 数据库":       "604007",
+	"云数据库 Redis":      "604008",
+	"云数据库 MongoDB":    "604009",
+	"云数据库 Memcached":  "604010",
+	"时序数据库":           "604011",
+	"游戏数据库":           "604012",
+	"图数据库 KonisGraph": "604013",
+	"负载均衡":            "605001",
+	"私有网络":            "605002",
+	"弹性网卡":            "605003",
+	"NAT 网关":          "605004",
+	"弹性公网 IP":         "605005",
+	"VPN 连接":          "605006",
+	"内容分发网络 CDN":      "605007",
+	"安全加速 SCDN":       "605008",
+	"数据分析":            "606001",
+	"日志服务":            "606002",
+	"弹性 MapReduce":    "606003",
+	"Elasticsearch服务": "606004",
+	"云数据仓库":           "606005",
+	"流计算":             "606006",
+	"数据湖分析":           "606007",
+	"数据湖计算":           "606008",
+	"数据编排平台":          "606009",
+	"开源大数据平台":         "606010",
+	"GPU云主机":          "607001",
+	"视频渲染服务":          "607002",
+	"DPU服务":           "607003",
+	"视觉计算":            "607004",
+	"自然语言处理":          "607005",
+	"内容推荐":            "607006",
+	"模型训练":            "607007",
+	"机器学习智算服务":        "607008",
+	"GPU智算服务":         "607009",
+	"超算服务":            "607010",
+	"弹性计算服务":          "607011",
+	"编解码服务":           "607012",
+	"边缘算力服务":          "608001",
+	"算力服务延时圈":         "608002",
+	"算力交易服务":          "608003",
+	"算网质量服务":          "608004",
+	"算网大脑服务":          "608005",
+	"其他":              "609001",
+}
+
+// Kubernetes 标签值只能包含字母、数字及 -_.，以下 ASCII 别名供节点标签使用，中文名称需写在注解或 label-config 中
+var (
+	ResourceTypeAliases = map[string]string{"hpc": "401", "ai": "402", "general": "403"}
+	DataCenterAliases   = map[string]string{
+		"az": "500", "az1": "501", "az2": "502", "az3": "503", "az4": "504",
+		"az5": "505", "az6": "506", "az7": "507", "az8": "508", "az9": "509", "az10": "510",
+	}
+)
+
+// nameTable 按归一化后的名称查找编码，也接受编码本身
+type nameTable struct {
+	kind  string
+	width int
+	// numeric 为 true 时接受表中没有的同宽度数字编码，以兼容附录新增的条目
+	numeric bool
+	names   map[string]string
+	codes   map[string]bool
+}
+
+func newNameTable(kind string, width int, numeric bool, tables ...map[string]string) *nameTable {
+	t := &nameTable{kind: kind, width: width, numeric: numeric, names: map[string]string{}, codes: map[string]bool{}}
+	for _, table := range tables {
+		for name, code := range table {
+			t.names[normalizeName(name)] = code
+			// 以“、”并列的名称，如“银行、金融”，各部分也可单独使用
+			for _, part := range strings.Split(name, "、") {
+				t.names[normalizeName(part)] = code
+			}
+			t.codes[code] = true
+		}
+	}
+	return t
+}
+
+// normalizeName 去除空白并转为小写，“GPU 云服务器”与“gpu云服务器”视为相同
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+func (t *nameTable) resolve(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("empty %s", t.kind)
+	}
+	if t.codes[s] || (t.numeric && len(s) == t.width && isDigits(s)) {
+		return s, nil
+	}
+	if code, ok := t.names[normalizeName(s)]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("unknown %s %q, expected a name from docs/powerresource.md or a %d-character code", t.kind, s, t.width)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+var (
+	cityTable         = newNameTable("city", 4, true, CityCodes)
+	industryTable     = newNameTable("industry", 2, false, IndustryCodes)
+	enterpriseTable   = newNameTable("enterprise", 5, true, EnterpriseCodes)
+	resourceTypeTable = newNameTable("resource type", 3, true, ResourceTypeCodes, ResourceTypeAliases)
+	dataCenterTable   = newNameTable("data center", 3, true, DataCenterCodes, DataCenterAliases)
+	serviceTypeTable  = newNameTable("service type", 6, true, ServiceTypeCodes)
+)
+
+// ResolveCity 接受城市名称（可带“市”后缀，如“北京市”）或 4 位编码
+func ResolveCity(s string) (string, error) {
+	if code, err := cityTable.resolve(strings.TrimSuffix(strings.TrimSpace(s), "市")); err == nil {
+		return code, nil
+	}
+	return cityTable.resolve(s)
+}
+
+// ResolveIndustry 接受行业名称或 2 位编码，如“计算机”或 cp
+func ResolveIndustry(s string) (string, error) {
+	return industryTable.resolve(s)
+}
+
+// ResolveEnterprise 接受企业名称或 5 位编码
+func ResolveEnterprise(s string) (string, error) {
+	return enterpriseTable.resolve(s)
+}
+
+// ResolveResourceType 接受资源类型名称、别名（hpc、ai、general）或 3 位编码
+func ResolveResourceType(s string) (string, error) {
+	return resourceTypeTable.resolve(s)
+}
+
+// ResolveDataCenter 接受数据中心名称、别名（az1 至 az10）或 3 位编码
+func ResolveDataCenter(s string) (string, error) {
+	return dataCenterTable.resolve(s)
+}
+
+// ServiceTypeCount 为服务类型字段中的服务数量，算力标识中该字段固定为 14 位
+const ServiceTypeCount = 2
+
+// ResolveServiceTypes 将以逗号（标签中可用 . 或 _）分隔的服务类型名称或 6 位编码组装为服务类型字段：
+// 2 位服务数量后接各服务的编码，如“容器服务,GPU 云服务器”为 02602001601004。已组装好的字段原样返回。
+// 服务数量不是 ServiceTypeCount 时返回错误
+func ResolveServiceTypes(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && isDigits(s) && (len(s)-2)%6 == 0 {
+		if count := (len(s) - 2) / 6; fmt.Sprintf("%02d", count) == s[:2] {
+			if count != ServiceTypeCount {
+				return "", fmt.Errorf("exactly %d service types are required, got %d in %q", ServiceTypeCount, count, s)
+			}
+			return s, nil
+		}
+	}
+
+	names := strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(",，;；._", r) })
+	if len(names) == 0 {
+		return "", fmt.Errorf("empty service type")
+	}
+	if len(names) != ServiceTypeCount {
+		return "", fmt.Errorf("exactly %d service types are required, got %d in %q", ServiceTypeCount, len(names), s)
+	}
+	codes := make([]string, len(names))
+	for i, name := range names {
+		code, err := serviceTypeTable.resolve(name)
+		if err != nil {
+			return "", err
+		}
+		codes[i] = code
+	}
+	return fmt.Sprintf("%02d", len(codes)) + strings.Join(codes, ""), nil
+}
@@ -1,9 +1,11 @@
 package apis
 
+// LabelInfo 为 label-config ConfigMap 中的静态字段名称，节点未设置对应标签或注解时使用
 type LabelInfo struct {
 	CityName       string
 	IndustryName   string
 	EnterpriseName string
 	ResourceType   string
+	DataCenterName string
 	ServiceType    string
 }
@@ -0,0 +1,40 @@
+package apis
+
+// MatchRequest 为任务对算力资源的需求，未设置的字段不参与筛选
+type MatchRequest struct {
+	// ChipType 为芯片类型名称（gpu、npu 等）或编码
+	ChipType  string `json:"chip_type,omitempty"`
+	ChipModel string `json:"chip_model,omitempty"`
+	// MinPFLOPs 为单台服务器需提供的最小计算量
+	MinPFLOPs float64 `json:"min_pflops,omitempty"`
+	// Selector 为地域等字段选择器，如 "city=1101,data-center=501"
+	Selector string `json:"selector,omitempty"`
+	// NetworkType 为网络类型名称（ib、roce 等）或编码
+	NetworkType   string `json:"network_type,omitempty"`
+	MaxPowerWatts int64  `json:"max_power_watts,omitempty"`
+	// Limit 为返回的候选数量，默认 20
+	Limit int `json:"limit,omitempty"`
+}
+
+// MatchCandidate 为同一服务器上同一型号芯片组成的候选资源
+type MatchCandidate struct {
+	ComputeIDs    []string `json:"compute_ids"`
+	City          string   `json:"city"`
+	Company       string   `json:"company"`
+	ResourceAZ    string   `json:"resource_az"`
+	ChipType      string   `json:"chip_type"`
+	ChipModel     string   `json:"chip_model"`
+	Chips         int      `json:"chips"`
+	ComputePFLOPs float64  `json:"compute_pflops"`
+	PowerWatts    int64    `json:"power_watts"`
+	NetworkType   string   `json:"network_type"`
+	Score         float64  `json:"score"`
+	// Explanation 逐项说明得分来源
+	Explanation []string `json:"explanation"`
+}
+
+type MatchResponse struct {
+	Candidates []MatchCandidate `json:"candidates"`
+	// Total 为满足全部硬性条件的候选总数
+	Total int `json:"total"`
+}
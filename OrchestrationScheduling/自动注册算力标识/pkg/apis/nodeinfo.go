@@ -1,22 +1,51 @@
 package apis
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ResourceIDMinLength 为算力标识的最小长度，芯片唯一编号至少保留 1 位
+const ResourceIDMinLength = 107
+
 type NodeResourceInfo struct {
-	ID                   string
-	City                 string
-	CompanyType          string
-	Company              string
-	ResourceType         string
-	ResourceAZ           string
-	ServiceType          string
-	ComputeCapacity      string
-	StorageCapacity      string
-	NetworkBandSwitch    string
-	PowerConsumption     string
-	NetworkType          string
-	PowerResourceAddress string
-	ChipType             string
-	ChipModel            string
-	ChipUniqNumber       string
+	ID                   string `json:"id"`
+	City                 string `json:"city"`
+	CompanyType          string `json:"company_type"`
+	Company              string `json:"company"`
+	ResourceType         string `json:"resource_type"`
+	ResourceAZ           string `json:"resource_az"`
+	ServiceType          string `json:"service_type"`
+	ComputeCapacity      string `json:"compute_capacity"`
+	StorageCapacity      string `json:"storage_capacity"`
+	NetworkBandSwitch    string `json:"network_band_switch"`
+	PowerConsumption     string `json:"power_consumption"`
+	NetworkType          string `json:"network_type"`
+	PowerResourceAddress string `json:"power_resource_address"`
+	ChipType             string `json:"chip_type"`
+	ChipModel            string `json:"chip_model"`
+	ChipUniqNumber       string `json:"chip_uniq_number"`
+}
+
+// ValidateResourceID 校验算力标识的长度及计算、存储、网络、功耗字段前缀，
+// 通过校验的标识才能交给 ParseResourceInfo 解析
+func ValidateResourceID(data string) error {
+	if len(data) < ResourceIDMinLength {
+		return fmt.Errorf("compute id %q is too short: expected at least %d characters, got %d",
+			data, ResourceIDMinLength, len(data))
+	}
+
+	prefixes := []struct {
+		offset int
+		prefix byte
+	}{{31, 'F'}, {36, 'S'}, {44, 'N'}, {51, 'P'}}
+	for _, p := range prefixes {
+		if data[p.offset] != p.prefix {
+			return fmt.Errorf("compute id %q: expected %q at offset %d, got %q", data, p.prefix, p.offset, data[p.offset])
+		}
+	}
+	return nil
 }
 
 func ParseResourceInfo(data string) *NodeResourceInfo {
@@ -57,3 +86,53 @@ func ResourceInfoToString(resource *NodeResourceInfo) string {
 		resource.ChipModel +
 		resource.ChipUniqNumber
 }
+
+// ResourceKey 返回算力标识去掉计算、存储、网络及功耗字段后的部分。
+// 同一芯片容量变化后重新上报时标识会改变，但 ResourceKey 保持不变。
+func ResourceKey(id string) string {
+	if len(id) < ResourceIDMinLength {
+		return id
+	}
+	return id[:31] + id[57:]
+}
+
+// ComputePFLOPs 返回芯片所在服务器的总计算量，单位 PFLOPs（见 CPID/算力标识体系更新版.md）
+func (r *NodeResourceInfo) ComputePFLOPs() int64 {
+	return parseCapacity(r.ComputeCapacity, "F")
+}
+
+// StorageGB 返回存储容量，单位 GB
+func (r *NodeResourceInfo) StorageGB() int64 {
+	return parseCapacity(r.StorageCapacity, "S")
+}
+
+// BandwidthMbps 返回网络带宽，单位 Mbps
+func (r *NodeResourceInfo) BandwidthMbps() int64 {
+	return parseCapacity(r.NetworkBandSwitch, "N")
+}
+
+// PowerWatts 返回芯片所在服务器的平均功耗，单位 W
+func (r *NodeResourceInfo) PowerWatts() int64 {
+	return parseCapacity(r.PowerConsumption, "P")
+}
+
+// IPv4 将算力互联网地址的后 32 位解码为点分十进制，无法解码时返回空串
+func (r *NodeResourceInfo) IPv4() string {
+	address := r.PowerResourceAddress
+	if len(address) < 32 {
+		return ""
+	}
+	value, err := strconv.ParseUint(address[len(address)-32:], 2, 32)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", value>>24, value>>16&0xff, value>>8&0xff, value&0xff)
+}
+
+func parseCapacity(field, prefix string) int64 {
+	value, err := strconv.ParseInt(strings.TrimPrefix(field, prefix), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package apis
+
+import (
+	"strings"
+	"testing"
+)
+
+const testResourceID = "1101tc20001401501" + "02602001601004" + "F0008S0001000N010000P02000" +
+	"01" + "00" + "11000000101010000000000100000001" + "00000" + "00000001" + "00001"
+
+func TestValidateResourceID(t *testing.T) {
+	if err := ValidateResourceID(testResourceID); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, id := range map[string]string{
+		"empty":          "",
+		"too short":      testResourceID[:ResourceIDMinLength-1],
+		"missing F":      testResourceID[:31] + "X" + testResourceID[32:],
+		"missing S":      testResourceID[:36] + "0" + testResourceID[37:],
+		"missing N":      testResourceID[:44] + "n" + testResourceID[45:],
+		"missing P":      testResourceID[:51] + "F" + testResourceID[52:],
+		"shifted fields": "0" + testResourceID,
+	} {
+		if err := ValidateResourceID(id); err == nil {
+			t.Errorf("%s: %q accepted", name, id)
+		}
+	}
+}
+
+func TestParseResourceInfo(t *testing.T) {
+	r := ParseResourceInfo(testResourceID)
+	if r.City != "1101" || r.Company != "20001" || r.ServiceType != "02602001601004" ||
+		r.ChipType != ChipTypeGPU || r.ChipModel != "00000001" || r.ChipUniqNumber != "00001" {
+		t.Fatalf("unexpected fields %+v", r)
+	}
+	if r.ComputePFLOPs() != 8 || r.StorageGB() != 1000 || r.BandwidthMbps() != 10000 || r.PowerWatts() != 2000 {
+		t.Fatalf("unexpected capacity %d %d %d %d", r.ComputePFLOPs(), r.StorageGB(), r.BandwidthMbps(), r.PowerWatts())
+	}
+	if ResourceInfoToString(r) != testResourceID {
+		t.Fatalf("round trip changed the id")
+	}
+	if key := ResourceKey(testResourceID); strings.Contains(key, "F0008") || len(key) != len(testResourceID)-26 {
+		t.Fatalf("resource key %q still contains the capacity segment", key)
+	}
+}
+
+func TestIPv4(t *testing.T) {
+	for address, want := range map[string]string{
+		// 前两位为地址类型，后 32 位为 IPv4
+		"00" + "11000000101010000000000100000001": "192.168.1.1",
+		"00" + "00001010000000000000000000000001": "10.0.0.1",
+		"0000000000000000000000000000001":         "",
+		"00" + "1100000010101000000000010000000x": "",
+	} {
+		r := &NodeResourceInfo{PowerResourceAddress: address}
+		if got := r.IPv4(); got != want {
+			t.Errorf("IPv4(%q) = %q, want %q", address, got, want)
+		}
+	}
+	if got := ParseResourceInfo(testResourceID).IPv4(); got != "192.168.1.1" {
+		t.Fatalf("unexpected address %q", got)
+	}
+}
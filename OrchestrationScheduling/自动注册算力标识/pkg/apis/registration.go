@@ -0,0 +1,63 @@
+package apis
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ComputeRegistrationResource 为 ComputeRegistration 的 GroupVersionResource，CRD 见 manifests/crds
+var ComputeRegistrationResource = schema.GroupVersionResource{
+	Group:    "cncos.org",
+	Version:  "v1alpha1",
+	Resource: "computeregistrations",
+}
+
+// ComputeRegistration 的 condition 类型
+const (
+	// ConditionReady 节点标签合法，算力标识已组装
+	ConditionReady = "Ready"
+	// ConditionRegistered 算力标识已被全部 server 确认
+	ConditionRegistered = "Registered"
+	// ConditionError 标签不合法或上报失败
+	ConditionError = "Error"
+)
+
+// ComputeRegistration 为集群级资源，记录一个节点（spec.nodeName）或整个集群（spec.nodeName 为空）的算力标识：
+// spec 为静态字段的覆盖，status 为实际组装、上报的结果
+type ComputeRegistration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComputeRegistrationSpec   `json:"spec,omitempty"`
+	Status ComputeRegistrationStatus `json:"status,omitempty"`
+}
+
+// ComputeRegistrationSpec 的静态字段可为编码或名称，解析规则与节点注解相同
+type ComputeRegistrationSpec struct {
+	// NodeName 为空时作为整个集群的默认值
+	NodeName     string `json:"nodeName,omitempty"`
+	City         string `json:"city,omitempty"`
+	Industry     string `json:"industry,omitempty"`
+	Enterprise   string `json:"enterprise,omitempty"`
+	ResourceType string `json:"resourceType,omitempty"`
+	DataCenter   string `json:"dataCenter,omitempty"`
+	ServiceType  string `json:"serviceType,omitempty"`
+}
+
+type ComputeRegistrationStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ComputeIDs 为节点按当前上报模式组装的算力标识，集群级资源不记录，只记录 ComputeIDCount
+	ComputeIDs []string `json:"computeIDs,omitempty"`
+	// ComputeIDCount 为算力标识数量，集群级资源为整个集群上报的数量
+	ComputeIDCount int                  `json:"computeIDCount"`
+	Servers        []ServerReportStatus `json:"servers,omitempty"`
+	Conditions     []metav1.Condition   `json:"conditions,omitempty"`
+}
+
+// ServerReportStatus 为向单个 server 上报的状态
+type ServerReportStatus struct {
+	Server string `json:"server"`
+	// LastReportTime 为最近一次成功发送注册或注销的时间
+	LastReportTime *metav1.Time `json:"lastReportTime,omitempty"`
+	LastError      string       `json:"lastError,omitempty"`
+}
@@ -0,0 +1,45 @@
+package apis
+
+// ResourceList 为分页查询结果，Revision 为查询时注册表的变更序号，可作为 watch 的起点
+type ResourceList struct {
+	Items    []*NodeResourceInfo `json:"items"`
+	Total    int                 `json:"total"`
+	Offset   int                 `json:"offset"`
+	Limit    int                 `json:"limit"`
+	Revision int64               `json:"revision"`
+}
+
+// 注册表变更事件类型
+const (
+	WatchEventAdded   = "added"
+	WatchEventUpdated = "updated"
+	WatchEventDeleted = "deleted"
+)
+
+// WatchEvent 为一次注册表变更，updated 事件的 Previous 为被替换的同一芯片的原有资源
+type WatchEvent struct {
+	Revision int64             `json:"revision"`
+	Type     string            `json:"type"`
+	Resource *NodeResourceInfo `json:"resource"`
+	Previous *NodeResourceInfo `json:"previous,omitempty"`
+}
+
+// WatchResponse 为一次长轮询的结果，下一次 watch 从 Revision 继续
+type WatchResponse struct {
+	Revision int64        `json:"revision"`
+	Events   []WatchEvent `json:"events"`
+}
+
+// RegistryStats 为注册表汇总，计算量、功耗按算力互联网地址去重，存储、带宽按数据中心去重后累加
+type RegistryStats struct {
+	Resources     int            `json:"resources"`
+	Servers       int            `json:"servers"`
+	ComputePFLOPs float64        `json:"compute_pflops"`
+	StorageGB     int64          `json:"storage_gb"`
+	BandwidthMbps int64          `json:"bandwidth_mbps"`
+	PowerWatts    int64          `json:"power_watts"`
+	ChipTypes     map[string]int `json:"chip_types"`
+	Cities        map[string]int `json:"cities"`
+	Enterprises   map[string]int `json:"enterprises"`
+	Revision      int64          `json:"revision"`
+}
@@ -0,0 +1,111 @@
+package apis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 选择器支持的算力标识字段
+const (
+	FieldCity         = "city"
+	FieldCompanyType  = "company-type"
+	FieldCompany      = "company"
+	FieldResourceType = "resource-type"
+	FieldResourceAZ   = "resource-az"
+	FieldServiceType  = "service-type"
+	FieldNetworkType  = "network-type"
+	FieldChipType     = "chip-type"
+	FieldChipModel    = "chip-model"
+)
+
+var fieldAliases = map[string]string{
+	"industry":    FieldCompanyType,
+	"enterprise":  FieldCompany,
+	"data-center": FieldResourceAZ,
+}
+
+var fieldValues = map[string]func(r *NodeResourceInfo) string{
+	FieldCity:         func(r *NodeResourceInfo) string { return r.City },
+	FieldCompanyType:  func(r *NodeResourceInfo) string { return r.CompanyType },
+	FieldCompany:      func(r *NodeResourceInfo) string { return r.Company },
+	FieldResourceType: func(r *NodeResourceInfo) string { return r.ResourceType },
+	FieldResourceAZ:   func(r *NodeResourceInfo) string { return r.ResourceAZ },
+	FieldServiceType:  func(r *NodeResourceInfo) string { return r.ServiceType },
+	FieldNetworkType:  func(r *NodeResourceInfo) string { return r.NetworkType },
+	FieldChipType:     func(r *NodeResourceInfo) string { return r.ChipType },
+	FieldChipModel:    func(r *NodeResourceInfo) string { return r.ChipModel },
+}
+
+var fieldNames = map[string]map[string]string{
+	FieldChipType:    ChipTypeNames,
+	FieldNetworkType: NetworkTypeNames,
+}
+
+// Selector 按算力标识前缀及字段取值筛选资源，零值匹配全部资源
+type Selector struct {
+	Prefix string
+	Fields map[string]string
+}
+
+// ParseSelector 解析形如 "city=1101,enterprise=20001,chip-type=00000" 的选择器，chip-type、network-type 可用名称
+func ParseSelector(s string) (Selector, error) {
+	selector := Selector{Fields: map[string]string{}}
+	if strings.TrimSpace(s) == "" {
+		return selector, nil
+	}
+
+	for _, term := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(term), "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return Selector{}, fmt.Errorf("invalid selector term %q, expected field=value", term)
+		}
+
+		field := strings.TrimSpace(parts[0])
+		if alias, ok := fieldAliases[field]; ok {
+			field = alias
+		}
+		if _, ok := fieldValues[field]; !ok {
+			return Selector{}, fmt.Errorf("unknown selector field %q", parts[0])
+		}
+		value := strings.TrimSpace(parts[1])
+		// 芯片类型、网络类型也可按名称筛选，如 chip-type=gpu
+		if names := fieldNames[field]; names != nil {
+			if code, ok := names[strings.ToLower(value)]; ok {
+				value = code
+			}
+		}
+		selector.Fields[field] = value
+	}
+	return selector, nil
+}
+
+func (s Selector) Empty() bool {
+	return s.Prefix == "" && len(s.Fields) == 0
+}
+
+func (s Selector) Matches(r *NodeResourceInfo) bool {
+	if !strings.HasPrefix(r.ID, s.Prefix) {
+		return false
+	}
+	for field, value := range s.Fields {
+		if fieldValues[field](r) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	terms := make([]string, 0, len(s.Fields))
+	for field, value := range s.Fields {
+		terms = append(terms, field+"="+value)
+	}
+	sort.Strings(terms)
+
+	str := strings.Join(terms, ",")
+	if s.Prefix != "" {
+		str = "prefix=" + s.Prefix + " " + str
+	}
+	return strings.TrimSpace(str)
+}
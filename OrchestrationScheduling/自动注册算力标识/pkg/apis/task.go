@@ -0,0 +1,45 @@
+package apis
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 任务执行结果
+const (
+	TaskOutcomeSucceeded = "succeeded"
+	TaskOutcomeFailed    = "failed"
+	TaskOutcomeCancelled = "cancelled"
+)
+
+// TaskTemplate 为平台提交的任务模板，Requirements 用于匹配算力资源，Data 保留平台自定义内容
+type TaskTemplate struct {
+	ID                string          `json:"id"`
+	Source            string          `json:"source"`
+	SessionIdentifier string          `json:"session_identifier"`
+	Requirements      MatchRequest    `json:"requirements"`
+	Data              json.RawMessage `json:"data,omitempty"`
+	// Owner 为创建模板的客户端身份，开启租户隔离时只有该客户端及 admin 可访问
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskStage 为任务路径中的一个阶段，记录该阶段在哪些算力标识上运行
+type TaskStage struct {
+	Name       string    `json:"name"`
+	ComputeIDs []string  `json:"compute_ids"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	// DurationSeconds 为阶段耗时，单位秒
+	DurationSeconds float64 `json:"duration_seconds"`
+	Outcome         string  `json:"outcome"`
+}
+
+// TaskPath 为一次任务执行的路径反馈
+type TaskPath struct {
+	TemplateID        string      `json:"template_id"`
+	Source            string      `json:"source"`
+	SessionIdentifier string      `json:"session_identifier"`
+	Stages            []TaskStage `json:"stages"`
+	Outcome           string      `json:"outcome"`
+	ReportedAt        time.Time   `json:"reported_at"`
+}
@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// AuthTypeClientCredentials 为 OAuth2 client credentials 认证
+const AuthTypeClientCredentials = "client_credentials"
+
+const (
+	defaultRefreshBefore = time.Minute
+	tokenRequestTimeout  = 30 * time.Second
+)
+
+// AuthConfig 为 server 的认证配置。密钥从文件读取（如挂载的 Kubernetes Secret），
+// 每次获取 token 时重新读取，Secret 轮换后无需重启
+type AuthConfig struct {
+	Type     string `json:"type"`
+	TokenURL string `json:"tokenURL"`
+	// ClientID 与 ClientIDFile 二选一
+	ClientID         string   `json:"clientID,omitempty"`
+	ClientIDFile     string   `json:"clientIDFile,omitempty"`
+	ClientSecretFile string   `json:"clientSecretFile"`
+	Scopes           []string `json:"scopes,omitempty"`
+	// AuthStyle 为 header（HTTP Basic）或 params（表单参数），为空时自动探测
+	AuthStyle string `json:"authStyle,omitempty"`
+	// RefreshBefore 为 token 过期前提前刷新的时间，如 "2m"，默认 1m
+	RefreshBefore string `json:"refreshBefore,omitempty"`
+}
+
+// Validate 校验认证配置，密钥文件在获取 token 时才读取
+func (a *AuthConfig) Validate() error {
+	if a.Type != AuthTypeClientCredentials {
+		return fmt.Errorf("unsupported auth type %q, expected %s", a.Type, AuthTypeClientCredentials)
+	}
+	if a.TokenURL == "" {
+		return fmt.Errorf("auth tokenURL must not be empty")
+	}
+	if (a.ClientID == "") == (a.ClientIDFile == "") {
+		return fmt.Errorf("exactly one of auth clientID and clientIDFile must be set")
+	}
+	if a.ClientSecretFile == "" {
+		return fmt.Errorf("auth clientSecretFile must not be empty")
+	}
+	switch a.AuthStyle {
+	case "", "header", "params":
+	default:
+		return fmt.Errorf("unknown auth style %q, expected header or params", a.AuthStyle)
+	}
+	if a.RefreshBefore != "" {
+		if _, err := time.ParseDuration(a.RefreshBefore); err != nil {
+			return fmt.Errorf("invalid auth refreshBefore: %v", err)
+		}
+	}
+	return nil
+}
+
+func (a *AuthConfig) refreshBefore() time.Duration {
+	if d, err := time.ParseDuration(a.RefreshBefore); err == nil && d > 0 {
+		return d
+	}
+	return defaultRefreshBefore
+}
+
+// fileTokenSource 每次从文件读取密钥后向 token 端点申请新 token
+type fileTokenSource struct {
+	auth *AuthConfig
+}
+
+func (s *fileTokenSource) Token() (*oauth2.Token, error) {
+	clientID := s.auth.ClientID
+	if s.auth.ClientIDFile != "" {
+		var err error
+		if clientID, err = readSecretFile(s.auth.ClientIDFile); err != nil {
+			return nil, err
+		}
+	}
+	clientSecret, err := readSecretFile(s.auth.ClientSecretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     s.auth.TokenURL,
+		Scopes:       s.auth.Scopes,
+	}
+	switch s.auth.AuthStyle {
+	case "header":
+		config.AuthStyle = oauth2.AuthStyleInHeader
+	case "params":
+		config.AuthStyle = oauth2.AuthStyleInParams
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: tokenRequestTimeout})
+	token, err := config.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching token from %s: %v", s.auth.TokenURL, err)
+	}
+	return token, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading secret file: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// token 按 server 缓存，drivers 每次调用都会重新创建，不能持有 token
+var (
+	tokenMutex   sync.Mutex
+	tokenSources = map[string]oauth2.TokenSource{}
+)
+
+// tokenCacheKey 包含认证配置的摘要，LoadConfig 重新加载后 client ID、scopes 等变化时使用新的 token
+func tokenCacheKey(config ServerConfig) string {
+	data, _ := json.Marshal(config.Auth)
+	sum := sha256.Sum256(data)
+	return config.ServerURL + " " + hex.EncodeToString(sum[:])
+}
+
+func serverToken(config ServerConfig) (*oauth2.Token, error) {
+	key := tokenCacheKey(config)
+	tokenMutex.Lock()
+	source, ok := tokenSources[key]
+	if !ok {
+		// 丢弃该 server 旧配置的 token
+		for cached := range tokenSources {
+			if strings.HasPrefix(cached, config.ServerURL+" ") {
+				delete(tokenSources, cached)
+			}
+		}
+		source = oauth2.ReuseTokenSourceWithExpiry(nil, &fileTokenSource{auth: config.Auth}, config.Auth.refreshBefore())
+		tokenSources[key] = source
+	}
+	tokenMutex.Unlock()
+	return source.Token()
+}
+
+// invalidateToken 丢弃缓存的 token，服务端返回 401 时调用，下次请求重新申请
+func invalidateToken(config ServerConfig) {
+	tokenMutex.Lock()
+	defer tokenMutex.Unlock()
+	delete(tokenSources, tokenCacheKey(config))
+}
+
+// authorize 为请求设置从文件读取的请求头及 OAuth2 token
+func authorize(config ServerConfig, req *http.Request) error {
+	for key, path := range config.HeaderFiles {
+		value, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading header %s: %v", key, err)
+		}
+		req.Header.Set(key, value)
+	}
+
+	if config.Auth == nil {
+		return nil
+	}
+	token, err := serverToken(config)
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
+
+// authTransport 为 Client 的请求加上 authorize 中的认证信息
+type authTransport struct {
+	config ServerConfig
+	base   http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := authorize(t.config, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && t.config.Auth != nil {
+		invalidateToken(t.config)
+	}
+	return resp, err
+}
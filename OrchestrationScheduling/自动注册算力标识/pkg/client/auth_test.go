@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientCredentialsTokenCachedAndRefreshed(t *testing.T) {
+	var issued int32
+	var expiresIn int32 = 3600
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, _ := r.BasicAuth()
+		if r.Form.Get("grant_type") != "client_credentials" || id != "controller" || secret != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   atomic.LoadInt32(&expiresIn),
+		})
+	}))
+	defer tokenServer.Close()
+
+	var lastAuth atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuth.Store(r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "message": "ok", "data": map[string]string{}})
+	}))
+	defer server.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "client-secret")
+	if err := ioutil.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := ServerConfig{ServerURL: server.URL + "/", Type: ServerTypeRemote, Auth: &AuthConfig{
+		Type:             AuthTypeClientCredentials,
+		TokenURL:         tokenServer.URL,
+		ClientID:         "controller",
+		ClientSecretFile: secretFile,
+		AuthStyle:        "header",
+	}}
+	if err := config.Auth.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	defer invalidateToken(config)
+
+	driver, _ := NewDriver(config)
+	for i := 0; i < 2; i++ {
+		if err := driver.Register(context.Background(), []string{testComputeID(1, 100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt32(&issued) != 1 || lastAuth.Load() != "Bearer token-1" {
+		t.Fatalf("expected one cached token, issued %d, last authorization %v", atomic.LoadInt32(&issued), lastAuth.Load())
+	}
+
+	// 有效期短于提前刷新时间的 token 每次都会重新申请
+	invalidateToken(config)
+	atomic.StoreInt32(&expiresIn, 30)
+	for i := 0; i < 2; i++ {
+		if err := driver.Register(context.Background(), []string{testComputeID(1, 100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if atomic.LoadInt32(&issued) != 3 || lastAuth.Load() != "Bearer token-3" {
+		t.Fatalf("expected tokens to be refreshed before expiry, issued %d, last authorization %v", atomic.LoadInt32(&issued), lastAuth.Load())
+	}
+}
+
+func TestTokenCacheFollowsAuthConfig(t *testing.T) {
+	var issued int32
+	var lastScope atomic.Value
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		lastScope.Store(r.Form.Get("scope"))
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "client-secret")
+	if err := ioutil.WriteFile(secretFile, []byte("s3cret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := ServerConfig{ServerURL: "http://resource-server.test/", Auth: &AuthConfig{
+		Type:             AuthTypeClientCredentials,
+		TokenURL:         tokenServer.URL,
+		ClientID:         "controller",
+		ClientSecretFile: secretFile,
+		Scopes:           []string{"register"},
+	}}
+	defer invalidateToken(config)
+
+	token := func(config ServerConfig) string {
+		t.Helper()
+		token, err := serverToken(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token.AccessToken
+	}
+	if first, second := token(config), token(config); first != "token-1" || second != "token-1" {
+		t.Fatalf("token not cached: %s, %s", first, second)
+	}
+
+	// 重新加载的配置修改了 scopes，不等 401 即申请新 token
+	reloaded := config
+	reloaded.Auth = &AuthConfig{}
+	*reloaded.Auth = *config.Auth
+	reloaded.Auth.Scopes = []string{"register", "unregister"}
+	defer invalidateToken(reloaded)
+	if got := token(reloaded); got != "token-2" || lastScope.Load() != "register unregister" {
+		t.Fatalf("reloaded config reused token %s with scope %v", got, lastScope.Load())
+	}
+	tokenMutex.Lock()
+	cached := len(tokenSources)
+	tokenMutex.Unlock()
+	if cached != 1 {
+		t.Fatalf("stale token sources kept: %d", cached)
+	}
+}
@@ -0,0 +1,250 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultWatchTimeout = 30 * time.Second
+)
+
+// ErrWatchExpired 表示 watch 的起点已不在服务端保留的变更范围内，需重新 List 后再 watch
+var ErrWatchExpired = errors.New("watch revision expired")
+
+// Config 为 Client 的配置，零值字段使用默认值
+type Config struct {
+	// ServerURL 为 resource-server 地址，如 http://resource-server.cncos-system.svc.cluster.local:8080
+	ServerURL string
+	// Headers 随每个请求发送
+	Headers map[string]string
+	// Timeout 为单个请求的超时时间，watch 请求在此基础上加上长轮询时间，默认 30s
+	Timeout time.Duration
+	// Transport 为底层 HTTP 传输，默认 http.DefaultTransport
+	Transport http.RoundTripper
+}
+
+// Client 为 resource-server 的 Go 客户端，可并发使用
+type Client struct {
+	serverURL  string
+	headers    map[string]string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+func NewClient(config Config) (*Client, error) {
+	if config.ServerURL == "" {
+		return nil, errors.New("server URL must not be empty")
+	}
+	if _, err := url.Parse(config.ServerURL); err != nil {
+		return nil, fmt.Errorf("invalid server URL: %v", err)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Client{
+		serverURL:  strings.TrimSuffix(config.ServerURL, "/"),
+		headers:    config.Headers,
+		timeout:    timeout,
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// APIError 为服务端返回的非预期状态码
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter 为 429 响应中 Retry-After 给出的重试间隔
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status code %d: %s", e.StatusCode, e.Message)
+}
+
+// Is 使 errors.Is(err, ErrNotFound) 可用于判断 404
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// ListOptions 为 List 的查询条件，Limit 为 0 时返回全部
+type ListOptions struct {
+	Prefix   string
+	Selector string
+	Offset   int
+	Limit    int
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, expected int, out interface{}) error {
+	return c.doWithTimeout(ctx, c.timeout, method, path, body, expected, out)
+}
+
+func (c *Client) doWithTimeout(ctx context.Context, timeout time.Duration, method, path string, body interface{},
+	expected int, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.serverURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expected {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
+
+// Register 注册算力标识，同一芯片容量变化后重新注册会替换原有标识
+func (c *Client) Register(ctx context.Context, computeIDs []string) error {
+	return c.do(ctx, "POST", "/resources", RequestBody{ComputeIDs: computeIDs}, http.StatusCreated, nil)
+}
+
+func (c *Client) Unregister(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/resources/"+url.PathEscape(id), nil, http.StatusNoContent, nil)
+}
+
+// UnregisterBatch 批量注销算力标识，返回服务端实际移除的数量
+func (c *Client) UnregisterBatch(ctx context.Context, ids []string) (int, error) {
+	var resp struct {
+		Removed int `json:"removed"`
+	}
+	if err := c.do(ctx, "POST", "/v1/resources/unregister", RequestBody{ComputeIDs: ids}, http.StatusOK, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Removed, nil
+}
+
+// Get 查询算力标识，不存在时返回的错误满足 errors.Is(err, ErrNotFound)
+func (c *Client) Get(ctx context.Context, id string) (*apis.NodeResourceInfo, error) {
+	resource := &apis.NodeResourceInfo{}
+	if err := c.do(ctx, "GET", "/v1/resources/"+url.PathEscape(id), nil, http.StatusOK, resource); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+func (c *Client) List(ctx context.Context, options ListOptions) (*apis.ResourceList, error) {
+	query := url.Values{}
+	if options.Prefix != "" {
+		query.Set("prefix", options.Prefix)
+	}
+	if options.Selector != "" {
+		query.Set("selector", options.Selector)
+	}
+	if options.Offset > 0 {
+		query.Set("offset", strconv.Itoa(options.Offset))
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+
+	list := &apis.ResourceList{}
+	if err := c.do(ctx, "GET", "/v1/resources?"+query.Encode(), nil, http.StatusOK, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *Client) Stats(ctx context.Context) (*apis.RegistryStats, error) {
+	stats := &apis.RegistryStats{}
+	if err := c.do(ctx, "GET", "/v1/stats", nil, http.StatusOK, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// WatchOnce 发起一次长轮询，返回 revision 之后的变更，没有变更时最多等待 timeout
+func (c *Client) WatchOnce(ctx context.Context, revision int64, timeout time.Duration) (*apis.WatchResponse, error) {
+	if timeout <= 0 {
+		timeout = defaultWatchTimeout
+	}
+	query := url.Values{
+		"revision": {strconv.FormatInt(revision, 10)},
+		"timeout":  {timeout.String()},
+	}
+
+	resp := &apis.WatchResponse{}
+	err := c.doWithTimeout(ctx, c.timeout+timeout, "GET", "/v1/watch?"+query.Encode(), nil, http.StatusOK, resp)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone {
+		return nil, ErrWatchExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Watch 从 revision 开始持续接收注册表变更并交给 handler 处理，
+// 直到 ctx 结束、handler 返回错误或起点过期（ErrWatchExpired）。
+// revision 通常取自 List 返回的 ResourceList.Revision。
+func (c *Client) Watch(ctx context.Context, revision int64, handler func(event apis.WatchEvent) error) error {
+	for {
+		resp, err := c.WatchOnce(ctx, revision, defaultWatchTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		for _, event := range resp.Events {
+			if err := handler(event); err != nil {
+				return err
+			}
+		}
+		revision = resp.Revision
+	}
+}
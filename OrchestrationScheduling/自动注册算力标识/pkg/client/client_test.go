@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"register-power-resources/pkg/apis"
+	"register-power-resources/pkg/server"
+)
+
+// newResourceServer 使用 resource-server 的处理函数启动测试服务，与 cmd/server 的路由一致
+func newResourceServer(t *testing.T) *httptest.Server {
+	router := mux.NewRouter()
+	router.HandleFunc("/resources", server.RegisterResource).Methods("POST")
+	router.HandleFunc("/resources/{id}", server.UnregisterResource).Methods("DELETE")
+	router.HandleFunc("/v1/resources/unregister", server.UnregisterResources).Methods("POST")
+	router.HandleFunc("/v1/resources", server.ListResourcesJSON).Methods("GET")
+	router.HandleFunc("/v1/resources/{id}", server.GetResourceJSON).Methods("GET")
+	router.HandleFunc("/v1/stats", server.Stats).Methods("GET")
+	router.Handle("/v1/watch", server.NewWatchHandler(server.NewOptions())).Methods("GET")
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Fatal("client created without server URL")
+	}
+
+	srv := newResourceServer(t)
+	c, err := NewClient(Config{ServerURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	a, b, other := testComputeID(1, 100), testComputeID(2, 100), testComputeID(3, 100)
+	if err := c.Register(ctx, []string{a, b, other}); err != nil {
+		t.Fatal(err)
+	}
+	var apiErr *APIError
+	if err := c.Register(ctx, []string{"invalid"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid compute ID, got %v", err)
+	}
+
+	resource, err := c.Get(ctx, a)
+	if err != nil || resource.ID != a {
+		t.Fatalf("unexpected resource %+v, %v", resource, err)
+	}
+
+	list, err := c.List(ctx, ListOptions{Offset: 1, Limit: 1})
+	if err != nil || list.Total != 3 || len(list.Items) != 1 || list.Revision == 0 {
+		t.Fatalf("unexpected list %+v, %v", list, err)
+	}
+
+	stats, err := c.Stats(ctx)
+	if err != nil || stats.Resources != 3 || stats.Servers != 1 {
+		t.Fatalf("unexpected stats %+v, %v", stats, err)
+	}
+
+	if err := c.Unregister(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, other); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after unregistering, got %v", err)
+	}
+	if removed, err := c.UnregisterBatch(ctx, []string{a, b, other}); err != nil || removed != 2 {
+		t.Fatalf("unexpected batch unregister result %d, %v", removed, err)
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+	srv := newResourceServer(t)
+	c, err := NewClient(Config{ServerURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	list, err := c.List(ctx, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.WatchOnce(ctx, list.Revision, 50*time.Millisecond)
+	if err != nil || len(resp.Events) != 0 || resp.Revision != list.Revision {
+		t.Fatalf("unexpected watch response %+v, %v", resp, err)
+	}
+
+	id := testComputeID(11, 100)
+	if err := c.Register(ctx, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UnregisterBatch(ctx, []string{id}); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	stop := errors.New("stop")
+	err = c.Watch(ctx, list.Revision, func(event apis.WatchEvent) error {
+		events = append(events, event.Type)
+		if event.Resource.ID != id {
+			t.Errorf("unexpected resource %s", event.Resource.ID)
+		}
+		if len(events) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(events) != 2 || events[0] != apis.WatchEventAdded || events[1] != apis.WatchEventDeleted {
+		t.Fatalf("unexpected watch result %v, events %v", err, events)
+	}
+
+	// ctx 结束时返回 ctx 的错误
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.Watch(cancelled, list.Revision, func(apis.WatchEvent) error { return nil }); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/watch":
+			http.Error(w, "revision is too old", http.StatusGone)
+		default:
+			w.Header().Set("Retry-After", "3")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+	c, err := NewClient(Config{ServerURL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var apiErr *APIError
+	err = c.Register(context.Background(), []string{testComputeID(1, 100)})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests ||
+		apiErr.RetryAfter != 3*time.Second || apiErr.Message != "rate limit exceeded" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Fatal("429 reported as ErrNotFound")
+	}
+	if _, err := c.WatchOnce(context.Background(), 1, time.Second); err != ErrWatchExpired {
+		t.Fatalf("expected ErrWatchExpired, got %v", err)
+	}
+}
@@ -10,22 +10,48 @@ type ServerConfig struct {
 	ServerURL string            `json:"serverURL"`
 	Type      string            `json:"type"`
 	Headers   map[string]string `json:"headers,omitempty"`
+	// HeaderFiles 为从文件读取取值的请求头，如挂载自 Secret 的 X-CLIENT-SECRET
+	HeaderFiles map[string]string `json:"headerFiles,omitempty"`
+	Auth        *AuthConfig       `json:"auth,omitempty"`
+	TLS         *TLSConfig        `json:"tls,omitempty"`
 }
 
+// DefaultConfigFile 为 server 配置的默认路径，由 ConfigMap 挂载
+const DefaultConfigFile = "./config/config.json"
+
 var serverConfigs []ServerConfig
 
 func LoadConfig() error {
-	// 从 ConfigMap 中读取 server 配置
-	data, err := ioutil.ReadFile("./config/config.json")
+	return LoadConfigFile(DefaultConfigFile)
+}
+
+// LoadConfigFile 从 path 读取 server 配置
+func LoadConfigFile(path string) error {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Error reading config.json: %v\n", err)
-		return err
+		return fmt.Errorf("error reading %s: %v", path, err)
 	}
 
 	err = json.Unmarshal(data, &serverConfigs)
 	if err != nil {
-		fmt.Printf("Error parsing config.json: %v\n", err)
-		return err
+		return fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	for _, config := range serverConfigs {
+		if config.Auth != nil {
+			if err := config.Auth.Validate(); err != nil {
+				return fmt.Errorf("invalid auth for %s: %v", config.ServerURL, err)
+			}
+		}
+		if config.TLS != nil {
+			if err := config.TLS.Validate(); err != nil {
+				return fmt.Errorf("invalid tls for %s: %v", config.ServerURL, err)
+			}
+		}
 	}
 	return nil
 }
+
+// SetServers 替换已加载的 server 配置，如命令行直接指定的 server
+func SetServers(configs []ServerConfig) {
+	serverConfigs = configs
+}
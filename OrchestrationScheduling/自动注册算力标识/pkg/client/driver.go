@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"register-power-resources/pkg/apis"
+)
+
+// 算力资源展示服务类型
+const (
+	// ServerTypeLocal 为本仓库的 resource-server
+	ServerTypeLocal = "local"
+	// ServerTypeRemote 为互联互通平台提供的算力标识接口
+	ServerTypeRemote = "remote"
+)
+
+var ErrNotFound = errors.New("resource not found")
+
+// ErrNotSupported 表示该服务类型没有对应的接口，如互联互通平台的注销、查询及列表；
+// 上报时注销不被支持视为失败，标识仍留在该 server 上
+var ErrNotSupported = errors.New("operation not supported by this server type")
+
+// Driver 封装一种服务类型的接口约定，config.json 中的每个 server 按 type 选择 Driver。
+// ctx 取消时应中止进行中的请求，单次请求另有超时，避免一个无响应的 server 阻塞上报
+type Driver interface {
+	Register(ctx context.Context, computeIDs []string) error
+	Unregister(ctx context.Context, id string) error
+	// UnregisterBatch 批量注销，不支持批量接口的服务逐个注销
+	UnregisterBatch(ctx context.Context, ids []string) error
+	Get(ctx context.Context, id string) (string, error)
+	List(ctx context.Context) ([]string, error)
+}
+
+var driverFactories = map[string]func(config ServerConfig) Driver{
+	ServerTypeLocal:  newLocalDriver,
+	ServerTypeRemote: newRemoteDriver,
+}
+
+// RegisterDriver 注册新的服务类型，同名类型会被覆盖
+func RegisterDriver(serverType string, factory func(config ServerConfig) Driver) {
+	driverFactories[serverType] = factory
+}
+
+func NewDriver(config ServerConfig) (Driver, error) {
+	factory, ok := driverFactories[config.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown server type %q for %s", config.Type, config.ServerURL)
+	}
+	return factory(config), nil
+}
+
+// Lister 为支持服务端按前缀、选择器筛选及分页的 Driver，其他 Driver 取回全部标识后在本地筛选
+type Lister interface {
+	ListResources(ctx context.Context, options ListOptions) (*apis.ResourceList, error)
+}
+
+// callServers 按重试策略对 config.json 中的每个 server 执行 fn，单个 server 出错不影响其他 server，
+// 成功时 ids 视为被该 server 接受
+func callServers(ctx context.Context, operation string, ids []string, fn func(driver Driver) error) RegistrationResult {
+	policy := currentRetryPolicy()
+	result := RegistrationResult{Operation: operation}
+	for _, config := range serverConfigs {
+		driver, err := NewDriver(config)
+		result.Servers = append(result.Servers, callServer(ctx, policy, config, driver, err, ids, fn))
+	}
+	return result
+}
+
+func callServer(ctx context.Context, policy RetryPolicy, config ServerConfig, driver Driver, err error,
+	ids []string, fn func(driver Driver) error) ServerResult {
+	server := ServerResult{ServerURL: config.ServerURL, Type: config.Type}
+	if err == nil {
+		server.Attempts, err = withRetry(ctx, config.ServerURL, policy, func() error { return fn(driver) })
+	}
+	if errors.Is(err, ErrNotSupported) {
+		server.Skipped = true
+		return server
+	}
+	if err != nil {
+		server.Err = err
+		fmt.Fprintf(os.Stderr, "Error from %s (%s) after %d attempts: %v\n", config.ServerURL, config.Type, server.Attempts, err)
+	} else {
+		server.Accepted = ids
+	}
+	return server
+}
+
+// forEachServer 对 config.json 中的每个 server 执行 fn，单个 server 出错不影响其他 server
+func forEachServer(fn func(config ServerConfig, driver Driver) error) {
+	for _, config := range serverConfigs {
+		driver, err := NewDriver(config)
+		if err == nil {
+			err = fn(config, driver)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error from %s (%s): %v\n", config.ServerURL, config.Type, err)
+		}
+	}
+}
+
+// doRequest 发送 req 并读取响应，单次请求最长 defaultTimeout，与 Client 一致
+func doRequest(config ServerConfig, req *http.Request) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), defaultTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	for key, value := range config.Headers {
+		req.Header.Set(key, value)
+	}
+	if err := authorize(config, req); err != nil {
+		return 0, nil, err
+	}
+
+	transport, err := serverTransport(config)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized && config.Auth != nil {
+		invalidateToken(config)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+func statusError(status int, body []byte) error {
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return &APIError{StatusCode: status, Message: string(body)}
+}
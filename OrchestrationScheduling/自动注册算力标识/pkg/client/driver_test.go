@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+func TestRemoteDriverRegister(t *testing.T) {
+	var body []byte
+	code := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/provider/compute_ids/" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		body, _ = io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": "invalid id", "data": map[string]string{}})
+	}))
+	defer srv.Close()
+
+	driver := newRemoteDriver(ServerConfig{ServerURL: srv.URL + "/api/v1/provider/compute_ids/", Type: ServerTypeRemote})
+	id := testComputeID(1, 100)
+	if err := driver.Register(context.Background(), []string{id}); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"compute_ids":["`+id+`"]}` {
+		t.Fatalf("unexpected request body %s", body)
+	}
+
+	// 非 0 code 为业务错误，不重试
+	code = 1001
+	err := driver.Register(context.Background(), []string{id})
+	var codeErr *remoteCodeError
+	if !errors.As(err, &codeErr) || codeErr.Code != 1001 || retryable(err) {
+		t.Fatalf("expected a non-retryable code error, got %v", err)
+	}
+}
+
+func TestRemoteDriverUnsupportedOperations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	driver := newRemoteDriver(ServerConfig{ServerURL: srv.URL + "/", Type: ServerTypeRemote})
+	id := testComputeID(1, 100)
+	if err := driver.Unregister(context.Background(), id); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Unregister: %v", err)
+	}
+	if err := driver.UnregisterBatch(context.Background(), []string{id}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("UnregisterBatch: %v", err)
+	}
+	if _, err := driver.Get(context.Background(), id); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := driver.List(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("List: %v", err)
+	}
+
+	// callServer 将不支持的操作标记为跳过，由调用方决定是否视为失败（上报时注销视为失败）
+	server := callServer(context.Background(), RetryPolicy{MaxAttempts: 1}, ServerConfig{ServerURL: srv.URL, Type: ServerTypeRemote}, driver, nil,
+		[]string{id}, func(d Driver) error { return d.UnregisterBatch(context.Background(), []string{id}) })
+	if !server.Skipped || server.Err != nil {
+		t.Fatalf("unexpected result %+v", server)
+	}
+}
+
+func TestLocalDriver(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{}}
+	// 在 fakeServer 之外补充单个注销、查询及列表接口
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "/resources/"):
+			fake.mutex.Lock()
+			delete(fake.resources, apis.ResourceKey(strings.TrimPrefix(path, "/resources/")))
+			fake.mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && path == "/v1/resources":
+			fake.mutex.Lock()
+			list := apis.ResourceList{}
+			for _, id := range fake.resources {
+				list.Items = append(list.Items, apis.ParseResourceInfo(id))
+			}
+			fake.mutex.Unlock()
+			list.Total = len(list.Items)
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/v1/resources/"):
+			id := strings.TrimPrefix(path, "/v1/resources/")
+			fake.mutex.Lock()
+			found := fake.resources[apis.ResourceKey(id)] == id
+			fake.mutex.Unlock()
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(apis.ParseResourceInfo(id))
+		default:
+			fake.ServeHTTP(w, r)
+		}
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	driver := newLocalDriver(ServerConfig{ServerURL: srv.URL, Type: ServerTypeLocal})
+	a, b := testComputeID(1, 100), testComputeID(2, 100)
+	if err := driver.Register(context.Background(), []string{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := driver.Get(context.Background(), a); err != nil || id != a {
+		t.Fatalf("Get returned %q: %v", id, err)
+	}
+	ids, err := driver.List(context.Background())
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("List returned %v: %v", ids, err)
+	}
+
+	if err := driver.Unregister(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := driver.Get(context.Background(), a); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after unregister, got %v", err)
+	}
+	if err := driver.UnregisterBatch(context.Background(), []string{b}); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := driver.List(context.Background()); err != nil || !reflect.DeepEqual(ids, []string{}) {
+		t.Fatalf("expected no resources, got %v: %v", ids, err)
+	}
+}
+
+func TestDriversCancelInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	// 无响应的 server 不会阻塞上报超过 ctx 的期限
+	for _, serverType := range []string{ServerTypeLocal, ServerTypeRemote} {
+		serverConfigs = []ServerConfig{{ServerURL: srv.URL, Type: serverType}}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		start := time.Now()
+		result := NewReporter(time.Hour).Report(ctx, []string{testComputeID(1, 100)}, nil)
+		cancel()
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Fatalf("%s: report blocked for %v", serverType, elapsed)
+		}
+		if result.Register.Err() == nil {
+			t.Fatalf("%s: expected an error from the stalled server", serverType)
+		}
+	}
+}
@@ -1,34 +1,36 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"os"
+
+	"register-power-resources/pkg/apis"
 )
 
-func GetResource(id string) {
+// GetResource 从 config.json 中的每个 server 查询算力标识，未注册的 server 返回的 Err 满足 errors.Is(err, ErrNotFound)
+func GetResource(id string) []ServerResources {
+	var results []ServerResources
 	for _, config := range serverConfigs {
-		if config.Type == "local" {
-			resp, err := http.Get(config.ServerURL + "/resources/" + id)
-			if err != nil {
-				fmt.Println("Error getting resource:", err)
-				return
+		result := ServerResources{ServerURL: config.ServerURL}
+		driver, err := NewDriver(config)
+		if err == nil {
+			var resource string
+			if resource, err = driver.Get(context.Background(), id); err == nil {
+				err = apis.ValidateResourceID(resource)
 			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				fmt.Println("Error getting resource: status code", resp.StatusCode)
-				return
+			if err == nil {
+				result.Items, result.Total = []*apis.NodeResourceInfo{apis.ParseResourceInfo(resource)}, 1
 			}
-
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				fmt.Println("Error reading response body:", err)
-				return
+		}
+		if err != nil {
+			result.Err = err
+			if !errors.Is(err, ErrNotFound) {
+				fmt.Fprintf(os.Stderr, "Error from %s (%s): %v\n", config.ServerURL, config.Type, err)
 			}
-
-			fmt.Println("Resource:", string(body))
 		}
+		results = append(results, result)
 	}
-
+	return results
 }
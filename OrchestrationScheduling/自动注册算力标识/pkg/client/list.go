@@ -1,65 +1,82 @@
 package client
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"reflect"
-	"register-power-resources/pkg/apis"
+	"os"
 	"strings"
-)
-
-func ListResources() {
-	for _, config := range serverConfigs {
-		if config.Type == "local" {
-			resp, err := http.Get(config.ServerURL + "/resources")
-			if err != nil {
-				fmt.Println("Error listing resources:", err)
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				fmt.Println("Error listing resources: status code", resp.StatusCode)
-				return
-			}
 
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				fmt.Println("Error reading response body:", err)
-				return
-			}
+	"register-power-resources/pkg/apis"
+)
 
-			if len(string(body)) != 0 {
-				fmt.Println("All Resources:")
-				fmt.Println(string(body))
+// ServerResources 为从单个 server 查询到的资源，Total 为分页前的匹配总数
+type ServerResources struct {
+	ServerURL string
+	Items     []*apis.NodeResourceInfo
+	Total     int
+	Err       error
+}
 
-				nodeInfos := strings.Split(string(body), "\n")
-				for _, node := range nodeInfos {
-					fmt.Println("==================================================================================" +
-						"===============================================")
-					fmt.Println("Resource detail for " + node + ":")
-					nodeInfo := apis.ParseResourceInfo(node)
-					printStructFields(nodeInfo)
-				}
-			}
+// ListResources 按 options 查询 config.json 中的每个 server，不支持服务端筛选的 server 在本地筛选及分页
+func ListResources(options ListOptions) []ServerResources {
+	var results []ServerResources
+	for _, config := range serverConfigs {
+		result := ServerResources{ServerURL: config.ServerURL}
+		driver, err := NewDriver(config)
+		if err == nil {
+			result.Items, result.Total, err = listServer(context.Background(), driver, options)
+		}
+		if err != nil {
+			result.Err = err
+			fmt.Fprintf(os.Stderr, "Error from %s (%s): %v\n", config.ServerURL, config.Type, err)
 		}
+		results = append(results, result)
 	}
+	return results
 }
 
-func printStructFields(obj interface{}) {
-	value := reflect.ValueOf(obj)
+func listServer(ctx context.Context, driver Driver, options ListOptions) ([]*apis.NodeResourceInfo, int, error) {
+	if lister, ok := driver.(Lister); ok {
+		list, err := lister.ListResources(ctx, options)
+		if err != nil {
+			return nil, 0, err
+		}
+		return list.Items, list.Total, nil
+	}
 
-	// 如果传入的对象是指针，获取指针指向的结构体值
-	if value.Kind() == reflect.Ptr {
-		value = value.Elem()
+	selector, err := apis.ParseSelector(options.Selector)
+	if err != nil {
+		return nil, 0, err
 	}
+	selector.Prefix = options.Prefix
 
-	typ := value.Type()
+	ids, err := driver.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	for i := 0; i < value.NumField(); i++ {
-		fieldName := typ.Field(i).Name
-		fieldValue := value.Field(i).Interface()
-		fmt.Printf("%s: %v\n", fieldName, fieldValue)
+	var matched []*apis.NodeResourceInfo
+	for _, id := range ids {
+		if !strings.HasPrefix(id, selector.Prefix) {
+			continue
+		}
+		if err := apis.ValidateResourceID(id); err != nil {
+			fmt.Fprintln(os.Stderr, "Skipping invalid compute id:", err)
+			continue
+		}
+		resource := apis.ParseResourceInfo(id)
+		if selector.Matches(resource) {
+			matched = append(matched, resource)
+		}
+	}
+
+	total := len(matched)
+	if options.Offset >= total {
+		return nil, total, nil
+	}
+	matched = matched[options.Offset:]
+	if options.Limit > 0 && options.Limit < len(matched) {
+		matched = matched[:options.Limit]
 	}
+	return matched, total, nil
 }
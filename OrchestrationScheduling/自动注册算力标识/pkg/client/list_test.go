@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+// idsDriver 只支持取回全部标识，ListResources 在本地筛选及分页
+type idsDriver []string
+
+func (d idsDriver) Register(context.Context, []string) error        { return ErrNotSupported }
+func (d idsDriver) Unregister(context.Context, string) error        { return ErrNotSupported }
+func (d idsDriver) UnregisterBatch(context.Context, []string) error { return ErrNotSupported }
+func (d idsDriver) Get(context.Context, string) (string, error)     { return "", ErrNotSupported }
+func (d idsDriver) List(context.Context) ([]string, error)          { return d, nil }
+
+func TestListServerFiltersLocally(t *testing.T) {
+	gpu1, gpu2 := testComputeID(1, 100), testComputeID(2, 100)
+	cpu := testComputeID(3, 100)
+	cpu = cpu[:93] + apis.ChipTypeCPU + cpu[98:]
+	other := "1201" + gpu1[4:]
+	driver := idsDriver{gpu1, "invalid", cpu, gpu2, other}
+
+	for _, c := range []struct {
+		name    string
+		options ListOptions
+		want    []string
+		total   int
+	}{
+		{"all", ListOptions{}, []string{gpu1, cpu, gpu2, other}, 4},
+		{"prefix", ListOptions{Prefix: "1101"}, []string{gpu1, cpu, gpu2}, 3},
+		{"selector", ListOptions{Selector: "city=1101,chip-type=gpu"}, []string{gpu1, gpu2}, 2},
+		{"page", ListOptions{Prefix: "1101", Offset: 1, Limit: 1}, []string{cpu}, 3},
+		{"last page", ListOptions{Prefix: "1101", Offset: 2, Limit: 5}, []string{gpu2}, 3},
+		{"offset past end", ListOptions{Offset: 10}, nil, 4},
+	} {
+		items, total, err := listServer(context.Background(), driver, c.options)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		var ids []string
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+		if total != c.total || len(ids) != len(c.want) {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", c.name, ids, total, c.want, c.total)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", c.name, ids, c.want)
+				break
+			}
+		}
+	}
+
+	if _, _, err := listServer(context.Background(), driver, ListOptions{Selector: "unknown=1"}); err == nil {
+		t.Fatal("invalid selector accepted")
+	}
+}
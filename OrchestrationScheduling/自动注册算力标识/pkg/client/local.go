@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+
+	"register-power-resources/pkg/apis"
+)
+
+// localDriver 通过 Client 访问本仓库的 resource-server
+type localDriver struct {
+	client *Client
+	err    error
+}
+
+func newLocalDriver(config ServerConfig) Driver {
+	transport, err := serverTransport(config)
+	if err != nil {
+		return &localDriver{err: err}
+	}
+	clientConfig := Config{ServerURL: config.ServerURL, Headers: config.Headers, Transport: transport}
+	if config.Auth != nil || len(config.HeaderFiles) > 0 {
+		clientConfig.Transport = &authTransport{config: config, base: transport}
+	}
+	client, err := NewClient(clientConfig)
+	return &localDriver{client: client, err: err}
+}
+
+func (d *localDriver) Register(ctx context.Context, computeIDs []string) error {
+	if d.err != nil {
+		return d.err
+	}
+	return d.client.Register(ctx, computeIDs)
+}
+
+func (d *localDriver) Unregister(ctx context.Context, id string) error {
+	if d.err != nil {
+		return d.err
+	}
+	return d.client.Unregister(ctx, id)
+}
+
+func (d *localDriver) UnregisterBatch(ctx context.Context, ids []string) error {
+	if d.err != nil {
+		return d.err
+	}
+	_, err := d.client.UnregisterBatch(ctx, ids)
+	return err
+}
+
+func (d *localDriver) Get(ctx context.Context, id string) (string, error) {
+	if d.err != nil {
+		return "", d.err
+	}
+	resource, err := d.client.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return resource.ID, nil
+}
+
+func (d *localDriver) List(ctx context.Context) ([]string, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	list, err := d.client.List(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(list.Items))
+	for _, resource := range list.Items {
+		ids = append(ids, resource.ID)
+	}
+	return ids, nil
+}
+
+func (d *localDriver) ListResources(ctx context.Context, options ListOptions) (*apis.ResourceList, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.client.List(ctx, options)
+}
@@ -0,0 +1,280 @@
+package client
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+// OutboxEntry 为一次未送达 server 的注册或注销操作
+type OutboxEntry struct {
+	Seq        uint64    `json:"seq"`
+	Server     string    `json:"server"`
+	Operation  string    `json:"operation"`
+	ComputeIDs []string  `json:"compute_ids"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Outbox 将未送达的操作按 server 保存在磁盘上，每个操作一个文件，文件名为递增序号，
+// 进程重启后仍可按原顺序重放
+type Outbox struct {
+	dir   string
+	mutex sync.Mutex
+	seq   uint64
+}
+
+const outboxFileSuffix = ".json"
+
+// outboxDeadDir 为 server 目录下保存无法送达（被 server 拒绝）操作的子目录，不再重放，需人工处理
+const outboxDeadDir = "dead"
+
+// MaxOutboxEntries 为每个 server 压缩后最多保留的操作数，超出时最早的操作移入 dead 目录
+const MaxOutboxEntries = 1000
+
+func NewOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating outbox directory: %v", err)
+	}
+
+	o := &Outbox{dir: dir}
+	serverDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, serverDir := range serverDirs {
+		if !serverDir.IsDir() {
+			continue
+		}
+		seqs, err := o.seqs(filepath.Join(dir, serverDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(seqs) > 0 && seqs[len(seqs)-1] > o.seq {
+			o.seq = seqs[len(seqs)-1]
+		}
+	}
+	return o, nil
+}
+
+func (o *Outbox) serverDir(server string) string {
+	sum := sha1.Sum([]byte(server))
+	return filepath.Join(o.dir, hex.EncodeToString(sum[:])[:16])
+}
+
+func (o *Outbox) seqs(dir string) ([]uint64, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, outboxFileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, outboxFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func (o *Outbox) entryPath(server string, seq uint64) string {
+	return filepath.Join(o.serverDir(server), fmt.Sprintf("%020d%s", seq, outboxFileSuffix))
+}
+
+// Enqueue 追加一个操作，先写临时文件再重命名，避免进程中断留下不完整的记录
+func (o *Outbox) Enqueue(server, operation string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	dir := o.serverDir(server)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	o.seq++
+	entry := OutboxEntry{Seq: o.seq, Server: server, Operation: operation, ComputeIDs: ids, EnqueuedAt: time.Now().UTC()}
+	return writeOutboxEntry(dir, o.entryPath(server, entry.Seq), entry)
+}
+
+// writeOutboxEntry 将 entry 写入 path，先写临时文件再重命名
+func writeOutboxEntry(dir, path string, entry OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing outbox entry: %v", err)
+	}
+	return nil
+}
+
+// Pending 按入队顺序返回 server 的待发送操作
+func (o *Outbox) Pending(server string) ([]OutboxEntry, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.pending(server)
+}
+
+func (o *Outbox) pending(server string) ([]OutboxEntry, error) {
+	seqs, err := o.seqs(o.serverDir(server))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]OutboxEntry, 0, len(seqs))
+	for _, seq := range seqs {
+		data, err := ioutil.ReadFile(o.entryPath(server, seq))
+		if err != nil {
+			return nil, err
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("error parsing outbox entry %d: %v", seq, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o *Outbox) Remove(server string, seq uint64) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if err := os.Remove(o.entryPath(server, seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeadLetter 将 server 的操作 seq 移入 dead 目录，之后不再重放
+func (o *Outbox) DeadLetter(server string, seq uint64) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.deadLetter(server, seq)
+}
+
+func (o *Outbox) deadLetter(server string, seq uint64) error {
+	dead := filepath.Join(o.serverDir(server), outboxDeadDir)
+	if err := os.MkdirAll(dead, 0755); err != nil {
+		return err
+	}
+	path := o.entryPath(server, seq)
+	return os.Rename(path, filepath.Join(dead, filepath.Base(path)))
+}
+
+// Compact 按 compactOutbox 重写 server 的待发送操作：删除已不含标识的操作，超过 MaxOutboxEntries 时将最早的操作移入 dead 目录。
+// 返回移入 dead 目录的操作数
+func (o *Outbox) Compact(server string) (int, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	entries, err := o.pending(server)
+	if err != nil {
+		return 0, err
+	}
+	var kept []OutboxEntry
+	for i, entry := range compactOutbox(entries) {
+		path := o.entryPath(server, entry.Seq)
+		switch {
+		case len(entry.ComputeIDs) == 0:
+			err = os.Remove(path)
+		case len(entry.ComputeIDs) != len(entries[i].ComputeIDs):
+			kept = append(kept, entry)
+			err = writeOutboxEntry(o.serverDir(server), path, entry)
+		default:
+			kept = append(kept, entry)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error compacting outbox entry %d: %v", entry.Seq, err)
+		}
+	}
+
+	dropped := 0
+	for ; len(kept)-dropped > MaxOutboxEntries; dropped++ {
+		if err := o.deadLetter(server, kept[dropped].Seq); err != nil {
+			return dropped, err
+		}
+	}
+	return dropped, nil
+}
+
+// Depth 返回 server 待发送的操作数
+func (o *Outbox) Depth(server string) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	seqs, _ := o.seqs(o.serverDir(server))
+	return len(seqs)
+}
+
+// compactOutbox 去掉被后续操作覆盖的标识：同一标识以最后一次操作为准，
+// 同一芯片（apis.ResourceKey）的多次注册只保留最后一次。返回的操作保持原有顺序，可能不含任何标识。
+func compactOutbox(entries []OutboxEntry) []OutboxEntry {
+	seenIDs := map[string]bool{}
+	seenKeys := map[string]bool{}
+	compacted := make([]OutboxEntry, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		var ids []string
+		for j := len(entry.ComputeIDs) - 1; j >= 0; j-- {
+			id := entry.ComputeIDs[j]
+			if seenIDs[id] {
+				continue
+			}
+			seenIDs[id] = true
+
+			if entry.Operation == "register" {
+				key := apis.ResourceKey(id)
+				if seenKeys[key] {
+					continue
+				}
+				seenKeys[key] = true
+			}
+			ids = append(ids, id)
+		}
+		for l, r := 0, len(ids)-1; l < r; l, r = l+1, r-1 {
+			ids[l], ids[r] = ids[r], ids[l]
+		}
+		entry.ComputeIDs = ids
+		compacted[i] = entry
+	}
+	return compacted
+}
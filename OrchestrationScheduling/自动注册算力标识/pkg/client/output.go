@@ -0,0 +1,134 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"register-power-resources/pkg/apis"
+	"sigs.k8s.io/yaml"
+)
+
+// 命令行输出格式
+const (
+	OutputTable = "table"
+	OutputWide  = "wide"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// ResourceView 为按字段解码后的算力标识，容量换算为数值，类型编码附带名称，地址解码为 IPv4
+type ResourceView struct {
+	Server          string `json:"server"`
+	ID              string `json:"id"`
+	City            string `json:"city"`
+	CompanyType     string `json:"company_type"`
+	Company         string `json:"company"`
+	ResourceType    string `json:"resource_type"`
+	ResourceAZ      string `json:"resource_az"`
+	ServiceType     string `json:"service_type"`
+	ComputePFLOPs   int64  `json:"compute_pflops"`
+	StorageGB       int64  `json:"storage_gb"`
+	BandwidthMbps   int64  `json:"bandwidth_mbps"`
+	PowerWatts      int64  `json:"power_watts"`
+	NetworkType     string `json:"network_type"`
+	NetworkTypeName string `json:"network_type_name"`
+	Address         string `json:"address"`
+	ChipType        string `json:"chip_type"`
+	ChipTypeName    string `json:"chip_type_name"`
+	ChipModel       string `json:"chip_model"`
+	ChipUniqNumber  string `json:"chip_uniq_number"`
+}
+
+func NewResourceView(server string, r *apis.NodeResourceInfo) ResourceView {
+	return ResourceView{
+		Server:          server,
+		ID:              r.ID,
+		City:            r.City,
+		CompanyType:     r.CompanyType,
+		Company:         r.Company,
+		ResourceType:    r.ResourceType,
+		ResourceAZ:      r.ResourceAZ,
+		ServiceType:     r.ServiceType,
+		ComputePFLOPs:   r.ComputePFLOPs(),
+		StorageGB:       r.StorageGB(),
+		BandwidthMbps:   r.BandwidthMbps(),
+		PowerWatts:      r.PowerWatts(),
+		NetworkType:     r.NetworkType,
+		NetworkTypeName: apis.CodeName(apis.NetworkTypeNames, r.NetworkType),
+		Address:         r.IPv4(),
+		ChipType:        r.ChipType,
+		ChipTypeName:    apis.CodeName(apis.ChipTypeNames, r.ChipType),
+		ChipModel:       r.ChipModel,
+		ChipUniqNumber:  r.ChipUniqNumber,
+	}
+}
+
+func ValidateOutputFormat(format string) error {
+	switch format {
+	case OutputTable, OutputWide, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected table, wide, json or yaml", format)
+}
+
+// PrintResources 按 format 输出各 server 查询到的资源。json、yaml 输出 ResourceView 数组；
+// table、wide 每行一个资源，分页未取完时在表格后注明各 server 的匹配总数
+func PrintResources(w io.Writer, format string, results []ServerResources) error {
+	var views []ResourceView
+	for _, result := range results {
+		for _, resource := range result.Items {
+			views = append(views, NewResourceView(result.ServerURL, resource))
+		}
+	}
+	if views == nil {
+		views = []ResourceView{}
+	}
+
+	switch format {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(views)
+	case OutputYAML:
+		data, err := yaml.Marshal(views)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case OutputTable, OutputWide:
+	default:
+		return ValidateOutputFormat(format)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if format == OutputWide {
+		fmt.Fprintln(tw, "SERVER\tID\tCITY\tCOMPANY-TYPE\tCOMPANY\tRESOURCE-TYPE\tAZ\tSERVICE-TYPE\tPFLOPS\tSTORAGE-GB\t"+
+			"BANDWIDTH-MBPS\tPOWER-W\tNETWORK\tADDRESS\tCHIP-TYPE\tCHIP-MODEL\tCHIP-UNIQ")
+	} else {
+		fmt.Fprintln(tw, "ID\tCITY\tCOMPANY\tCHIP-TYPE\tPFLOPS\tADDRESS")
+	}
+	for _, v := range views {
+		if format == OutputWide {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				v.Server, v.ID, v.City, v.CompanyType, v.Company, v.ResourceType, v.ResourceAZ, v.ServiceType,
+				v.ComputePFLOPs, v.StorageGB, v.BandwidthMbps, v.PowerWatts, v.NetworkTypeName, v.Address,
+				v.ChipTypeName, v.ChipModel, v.ChipUniqNumber)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+				v.ID, v.City, v.Company, v.ChipTypeName, v.ComputePFLOPs, v.Address)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Err == nil && result.Total > len(result.Items) {
+			fmt.Fprintf(w, "# %d of %d resources from %s\n", len(result.Items), result.Total, result.ServerURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+	"sigs.k8s.io/yaml"
+)
+
+func TestPrintResources(t *testing.T) {
+	a, b := testComputeID(1, 100), testComputeID(2, 100)
+	results := []ServerResources{
+		{ServerURL: "http://a", Items: []*apis.NodeResourceInfo{apis.ParseResourceInfo(a)}, Total: 3},
+		{ServerURL: "http://b", Items: []*apis.NodeResourceInfo{apis.ParseResourceInfo(b)}, Total: 1},
+	}
+
+	var out bytes.Buffer
+	if err := PrintResources(&out, OutputTable, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || strings.Join(strings.Fields(lines[0]), " ") != "ID CITY COMPANY CHIP-TYPE PFLOPS ADDRESS" ||
+		strings.Join(strings.Fields(lines[1]), " ") != a+" 1101 20001 gpu 100 0.0.0.1" {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	// 分页未取完的 server 注明匹配总数
+	if lines[3] != "# 1 of 3 resources from http://a" {
+		t.Fatalf("unexpected footer %q", lines[3])
+	}
+
+	out.Reset()
+	if err := PrintResources(&out, OutputWide, results); err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if fields := strings.Fields(lines[0]); len(fields) != 17 || fields[0] != "SERVER" {
+		t.Fatalf("unexpected wide header %q", lines[0])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 17 || fields[0] != "http://b" || fields[1] != b {
+		t.Fatalf("unexpected wide row %q", lines[2])
+	}
+
+	for _, format := range []string{OutputJSON, OutputYAML} {
+		out.Reset()
+		if err := PrintResources(&out, format, results); err != nil {
+			t.Fatal(err)
+		}
+		var views []ResourceView
+		var err error
+		if format == OutputJSON {
+			err = json.Unmarshal(out.Bytes(), &views)
+		} else {
+			err = yaml.Unmarshal(out.Bytes(), &views)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(views) != 2 || views[0] != NewResourceView("http://a", apis.ParseResourceInfo(a)) ||
+			views[1].Server != "http://b" || views[1].ChipTypeName != "gpu" || views[1].Address != "0.0.0.1" {
+			t.Fatalf("%s: unexpected views %+v", format, views)
+		}
+	}
+
+	// 没有资源时输出空数组而非 null
+	out.Reset()
+	if err := PrintResources(&out, OutputJSON, nil); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Fatalf("unexpected empty output %q, %v", out.String(), err)
+	}
+	if err := PrintResources(&out, "xml", results); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
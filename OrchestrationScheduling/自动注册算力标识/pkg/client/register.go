@@ -2,67 +2,77 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"register-power-resources/pkg/apis"
 )
 
 type RequestBody struct {
 	ComputeIDs []string `json:"compute_ids"`
 }
 
-func RegisterResource(computeIDs []string) {
-	//准备请求 body
-
-	body := struct {
-		ComputeIDs []string `json:"compute_ids"`
-	}{
-		ComputeIDs: computeIDs,
+// RegisterResource 向 config.json 中的每个 server 注册算力标识，失败时按重试策略重试，
+// 返回各 server 接受了哪些标识
+func RegisterResource(computeIDs []string) RegistrationResult {
+	result := callServers(context.Background(), "register", computeIDs, func(driver Driver) error {
+		return driver.Register(context.Background(), computeIDs)
+	})
+	for _, server := range result.Servers {
+		if server.Err == nil {
+			fmt.Printf("Registered %d resources to %s\n", len(server.Accepted), server.ServerURL)
+		}
 	}
+	return result
+}
 
-	bodyBytes, err := json.Marshal(body)
+// ReadComputeIDs 读取待注册的算力标识，支持 JSON 数组、{"compute_ids": [...]}，
+// 或每行一个（也可用空格、逗号分隔）的文本，文本中 # 开头的行为注释。所有标识都通过校验时才返回
+func ReadComputeIDs(r io.Reader) ([]string, error) {
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		fmt.Printf("Error preparing request body: %v\n", err)
-		return
+		return nil, fmt.Errorf("error reading compute ids: %v", err)
 	}
+	data = bytes.TrimSpace(data)
 
-	// 向每个 server 发送 POST 请求
-	for _, config := range serverConfigs {
-		serverURL := config.ServerURL
-
-		if config.Type == "local" {
-			serverURL = config.ServerURL + "/resources"
+	var ids []string
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		if err := json.Unmarshal(data, &ids); err != nil {
+			return nil, fmt.Errorf("error parsing compute ids: %v", err)
 		}
-
-		fmt.Println(serverURL)
-
-		req, err := http.NewRequest("POST", serverURL, bytes.NewBuffer(bodyBytes))
-		if err != nil {
-			fmt.Printf("Error creating request for %s: %v\n", config.ServerURL, err)
-			continue
+	case bytes.HasPrefix(data, []byte("{")):
+		var body RequestBody
+		if err := json.Unmarshal(data, &body); err != nil {
+			return nil, fmt.Errorf("error parsing compute ids: %v", err)
 		}
-
-		if config.Type == "local" {
-			req.Header.Set("Content-Type", "application/json")
+		ids = body.ComputeIDs
+	default:
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				continue
+			}
+			ids = append(ids, strings.FieldsFunc(line, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t' || r == '\r'
+			})...)
 		}
+	}
 
-		for key, value := range config.Headers {
-			req.Header.Set(key, value)
-		}
-
-		fmt.Println("request messages begin")
-		fmt.Println(req)
-		fmt.Println("request messages end")
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			fmt.Printf("Error sending request to %s: %v\n", config.ServerURL, err)
-			continue
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no compute ids given")
+	}
+	var invalid []string
+	for _, id := range ids {
+		if err := apis.ValidateResourceID(id); err != nil {
+			invalid = append(invalid, err.Error())
 		}
-		defer resp.Body.Close()
-
-		fmt.Printf("Response from %s: %s\n", config.ServerURL, resp.Status)
-		fmt.Println(resp)
 	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%d invalid compute ids:\n%s", len(invalid), strings.Join(invalid, "\n"))
+	}
+	return ids, nil
 }
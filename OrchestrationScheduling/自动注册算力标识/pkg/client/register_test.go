@@ -0,0 +1,37 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadComputeIDs(t *testing.T) {
+	a, b := testComputeID(1, 100), testComputeID(2, 100)
+	for name, input := range map[string]string{
+		"json array":   `["` + a + `", "` + b + `"]`,
+		"request body": `{"compute_ids": ["` + a + `", "` + b + `"]}`,
+		"lines":        "# 注释\n" + a + "\r\n\n" + b + "\n",
+		"separators":   a + ", " + b,
+	} {
+		ids, err := ReadComputeIDs(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(ids, []string{a, b}) {
+			t.Errorf("%s: unexpected ids %v", name, ids)
+		}
+	}
+
+	for name, input := range map[string]string{
+		"empty":         "  \n",
+		"only comments": "# " + a,
+		"invalid json":  `["` + a + `"`,
+		"invalid id":    a + "\ninvalid",
+	} {
+		if ids, err := ReadComputeIDs(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: accepted as %v", name, ids)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// remoteDriver 访问互联互通平台的算力标识接收接口，serverURL 为接收地址（如 .../api/v1/provider/compute_ids/）。
+// 注册为 POST {serverURL}，请求体 {"compute_ids": [...]}，与原有的 RegisterResource 一致，认证头由 config.json 的 headers、headerFiles 配置；
+// 这不是 CPID/cnc-app/backend/docs/apis.md 中的 POST /api/v1/computing_ids/（请求体 computing_ids、token 请求头），
+// 只有响应与其相同，统一封装为 {"code": 0, "message": "ok", "data": {}}，code 非 0 表示失败。
+// 平台没有注销、查询及列表接口，这些操作返回 ErrNotSupported，上报时注销被视为失败
+type remoteDriver struct {
+	config ServerConfig
+}
+
+func newRemoteDriver(config ServerConfig) Driver {
+	return &remoteDriver{config: config}
+}
+
+// remoteCodeError 为平台以非 0 code 返回的业务错误，重试不会改变结果
+type remoteCodeError struct {
+	Code    int
+	Message string
+}
+
+func (e *remoteCodeError) Error() string {
+	return fmt.Sprintf("code %d: %s", e.Code, e.Message)
+}
+
+// remoteResponse 为互联互通平台的统一响应结构
+type remoteResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (d *remoteDriver) Register(ctx context.Context, computeIDs []string) error {
+	data, err := json.Marshal(RequestBody{ComputeIDs: computeIDs})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", d.config.ServerURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+
+	status, body, err := doRequest(d.config, req)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return statusError(status, body)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	var resp remoteResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+	if resp.Code != 0 {
+		return &remoteCodeError{Code: resp.Code, Message: resp.Message}
+	}
+	return nil
+}
+
+func (d *remoteDriver) Unregister(ctx context.Context, id string) error {
+	return ErrNotSupported
+}
+
+func (d *remoteDriver) UnregisterBatch(ctx context.Context, ids []string) error {
+	return ErrNotSupported
+}
+
+func (d *remoteDriver) Get(ctx context.Context, id string) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (d *remoteDriver) List(ctx context.Context) ([]string, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,455 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+// 全量同步的原因
+const (
+	ResyncInitial  = "initial"
+	ResyncInterval = "interval"
+	ResyncFailure  = "failure"
+	ResyncMismatch = "mismatch"
+)
+
+// Reporter 记录每个 server 已确认的算力标识，每次上报只发送新增、变化及移除的部分。
+// 首次上报、距上次全量同步超过 FullResyncInterval、上次上报失败或 server 上缺少已确认的标识（如 server 重启）时做全量同步。
+type Reporter struct {
+	FullResyncInterval time.Duration
+	// Outbox 不为空时，未送达的操作保存在磁盘上，下次上报时按顺序重放
+	Outbox *Outbox
+
+	mutex   sync.Mutex
+	servers map[string]*serverState
+}
+
+// serverState 为单个 server 已确认的状态，以 apis.ResourceKey 为键：acked 包括已排入 outbox 的变化，用于计算下次上报的增量；
+// confirmed 只包括 server 实际接收的标识
+type serverState struct {
+	acked      map[string]string
+	confirmed  map[string]string
+	lastFull   time.Time
+	dirty      bool
+	lastReport time.Time
+	lastErr    error
+}
+
+// ServerStatus 为单个 server 的上报状态
+type ServerStatus struct {
+	ServerURL string
+	// LastReport 为最近一次成功发送注册或注销的时间，没有变化时不发送请求
+	LastReport time.Time
+	// LastError 为最近一次上报的错误，上报成功后清空
+	LastError error
+}
+
+func NewReporter(fullResyncInterval time.Duration) *Reporter {
+	return &Reporter{FullResyncInterval: fullResyncInterval, servers: map[string]*serverState{}}
+}
+
+// ReportResult 为一次上报的结果，Register、Unregister 分别汇总各 server 的注册及注销结果
+type ReportResult struct {
+	Register   RegistrationResult
+	Unregister RegistrationResult
+	// FullResync 为做了全量同步的 server 及原因
+	FullResync map[string]string
+}
+
+// Report 上报期望注册的 desired 及需要注销的 unregister，desired 中没有而已确认的标识也会被注销
+func (r *Reporter) Report(ctx context.Context, desired, unregister []string) ReportResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	want := make(map[string]string, len(desired))
+	for _, id := range desired {
+		want[apis.ResourceKey(id)] = id
+	}
+
+	policy := currentRetryPolicy()
+	result := ReportResult{
+		Register:   RegistrationResult{Operation: "register"},
+		Unregister: RegistrationResult{Operation: "unregister"},
+		FullResync: map[string]string{},
+	}
+	for _, config := range serverConfigs {
+		driver, err := NewDriver(config)
+		state, ok := r.servers[config.ServerURL]
+		if !ok {
+			state = &serverState{acked: map[string]string{}, confirmed: map[string]string{}}
+			r.servers[config.ServerURL] = state
+		}
+
+		var add, remove []string
+		reason := r.resyncReason(state, ok)
+		if reason == "" && err == nil && missingOnServer(ctx, driver, state.confirmed) {
+			reason = ResyncMismatch
+		}
+		if reason != "" {
+			result.FullResync[config.ServerURL] = reason
+			add = append(add, desired...)
+			remove = append(remove, unregister...)
+			for key, id := range state.acked {
+				if _, ok := want[key]; !ok {
+					remove = append(remove, id)
+				}
+			}
+		} else {
+			add, remove = delta(state.acked, want)
+		}
+
+		remove = dedupe(remove)
+
+		// 先重放积压的操作；仍无法送达时本次变化也排入 outbox，保证按顺序送达
+		if r.Outbox != nil && !r.flushOutbox(ctx, policy, config, driver, err, state, &result) {
+			r.postpone(state, config.ServerURL, add, remove, reason)
+			continue
+		}
+
+		// 先注册再注销：容量变化时服务端按芯片替换原有标识，不会出现短暂缺失
+		if len(add) > 0 {
+			server := callServer(ctx, policy, config, driver, err, add, func(d Driver) error {
+				return d.Register(ctx, add)
+			})
+			result.Register.Servers = append(result.Register.Servers, server)
+			if server.Err != nil {
+				r.postpone(state, config.ServerURL, add, remove, reason)
+				continue
+			}
+			state.apply(add, nil)
+			state.confirm(add, nil)
+		}
+
+		if len(remove) > 0 {
+			server := unsupportedRemoval(callServer(ctx, policy, config, driver, err, remove, func(d Driver) error {
+				return d.UnregisterBatch(ctx, remove)
+			}), remove)
+			result.Unregister.Servers = append(result.Unregister.Servers, server)
+			switch {
+			case errors.Is(server.Err, ErrNotSupported):
+				// 重放同样无法送达，不排入 outbox；标识留在 acked 中，之后每次上报都会再次报告
+			case server.Err != nil:
+				r.postpone(state, config.ServerURL, nil, remove, reason)
+				continue
+			default:
+				state.apply(nil, remove)
+				state.confirm(nil, remove)
+			}
+		}
+
+		state.dirty = false
+		if reason != "" {
+			state.lastFull = time.Now()
+		}
+	}
+	r.recordServers(result, time.Now())
+	return result
+}
+
+// Teardown 同步注销 ids、已确认的全部标识及 outbox 中积压的全部标识，不经过 outbox：调用方（如被移除的集群）之后不再上报，
+// 排入 outbox 的操作不会被重放。某个 server 注销成功后清空其 outbox，失败时保留 outbox 并在结果中返回错误
+func (r *Reporter) Teardown(ctx context.Context, ids []string) RegistrationResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	policy := currentRetryPolicy()
+	result := RegistrationResult{Operation: "unregister"}
+	for _, config := range serverConfigs {
+		driver, err := NewDriver(config)
+		remove := append([]string(nil), ids...)
+		if state, ok := r.servers[config.ServerURL]; ok {
+			for _, id := range state.acked {
+				remove = append(remove, id)
+			}
+		}
+
+		var pending []OutboxEntry
+		if r.Outbox != nil {
+			var readErr error
+			if pending, readErr = r.Outbox.Pending(config.ServerURL); readErr != nil {
+				result.Servers = append(result.Servers, ServerResult{ServerURL: config.ServerURL, Type: config.Type,
+					Err: fmt.Errorf("error reading outbox: %v", readErr)})
+				continue
+			}
+			for _, entry := range pending {
+				remove = append(remove, entry.ComputeIDs...)
+			}
+		}
+		remove = dedupe(remove)
+		if len(remove) == 0 {
+			continue
+		}
+
+		server := unsupportedRemoval(callServer(ctx, policy, config, driver, err, remove, func(d Driver) error {
+			return d.UnregisterBatch(ctx, remove)
+		}), remove)
+		result.Servers = append(result.Servers, server)
+		if server.Err != nil {
+			continue
+		}
+		delete(r.servers, config.ServerURL)
+		for _, entry := range pending {
+			if err := r.Outbox.Remove(config.ServerURL, entry.Seq); err != nil {
+				fmt.Printf("Error removing outbox entry %d for %s: %v\n", entry.Seq, config.ServerURL, err)
+			}
+		}
+	}
+	return result
+}
+
+// unsupportedRemoval 将不支持注销的 server（如互联互通平台）跳过的注销视为失败：标识仍留在该 server 上，不能当作已注销
+func unsupportedRemoval(server ServerResult, ids []string) ServerResult {
+	if server.Skipped {
+		server.Skipped = false
+		server.Err = fmt.Errorf("%d compute ids remain registered on %s: %w", len(ids), server.ServerURL, ErrNotSupported)
+	}
+	return server
+}
+
+// recordServers 按本次结果更新各 server 最近一次成功上报的时间及错误
+func (r *Reporter) recordServers(result ReportResult, now time.Time) {
+	sent := map[string]bool{}
+	failed := map[string]error{}
+	for _, servers := range [][]ServerResult{result.Register.Servers, result.Unregister.Servers} {
+		for _, server := range servers {
+			if server.Err != nil {
+				failed[server.ServerURL] = server.Err
+			} else {
+				sent[server.ServerURL] = true
+			}
+		}
+	}
+	for url, state := range r.servers {
+		state.lastErr = failed[url]
+		if state.lastErr == nil && sent[url] {
+			state.lastReport = now
+		}
+	}
+}
+
+// ServerStatuses 返回各 server 的上报状态，按地址排序
+func (r *Reporter) ServerStatuses() []ServerStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	statuses := make([]ServerStatus, 0, len(r.servers))
+	for url, state := range r.servers {
+		statuses = append(statuses, ServerStatus{ServerURL: url, LastReport: state.lastReport, LastError: state.lastErr})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ServerURL < statuses[j].ServerURL })
+	return statuses
+}
+
+// Acknowledged 返回 ids 是否都已被 server 实际接收，排入 outbox 尚未送达的不算
+func (r *Reporter) Acknowledged(server string, ids []string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	state, ok := r.servers[server]
+	if !ok {
+		return false
+	}
+	for _, id := range ids {
+		if state.confirmed[apis.ResourceKey(id)] != id {
+			return false
+		}
+	}
+	return true
+}
+
+// apply 将已送达（或已可靠排入 outbox）的变化记入 acked
+func (s *serverState) apply(add, remove []string) {
+	applyIDs(s.acked, add, remove)
+}
+
+// confirm 将 server 实际接收的变化记入 confirmed
+func (s *serverState) confirm(add, remove []string) {
+	applyIDs(s.confirmed, add, remove)
+}
+
+func applyIDs(ids map[string]string, add, remove []string) {
+	for _, id := range add {
+		ids[apis.ResourceKey(id)] = id
+	}
+	for _, id := range remove {
+		if ids[apis.ResourceKey(id)] == id {
+			delete(ids, apis.ResourceKey(id))
+		}
+	}
+}
+
+// postpone 处理未送达的变化：有 outbox 时排入 outbox 并视为已确认，否则下次全量同步
+func (r *Reporter) postpone(state *serverState, server string, add, remove []string, reason string) {
+	if r.Outbox != nil {
+		err := r.Outbox.Enqueue(server, "register", add)
+		if err == nil {
+			err = r.Outbox.Enqueue(server, "unregister", remove)
+		}
+		if err == nil {
+			var dropped int
+			if dropped, err = r.Outbox.Compact(server); dropped > 0 {
+				fmt.Printf("[Error]Outbox for %s exceeds %d operations, %d oldest moved to dead letters\n", server, MaxOutboxEntries, dropped)
+			}
+		}
+		if err == nil {
+			state.apply(add, remove)
+			state.dirty = false
+			if reason != "" {
+				state.lastFull = time.Now()
+			}
+			return
+		}
+		fmt.Printf("Error queueing pending operations for %s: %v\n", server, err)
+	}
+	state.dirty = true
+}
+
+// flushOutbox 按入队顺序重放 server 积压的操作，全部送达时返回 true。
+// 被 server 拒绝（不可重试的错误）的操作移入 dead 目录后继续重放后续操作，避免阻塞整个队列
+func (r *Reporter) flushOutbox(ctx context.Context, policy RetryPolicy, config ServerConfig, driver Driver, err error,
+	state *serverState, result *ReportResult) bool {
+	pending, readErr := r.Outbox.Pending(config.ServerURL)
+	if readErr != nil {
+		fmt.Printf("Error reading outbox for %s: %v\n", config.ServerURL, readErr)
+		return false
+	}
+
+	for _, entry := range compactOutbox(pending) {
+		ids := entry.ComputeIDs
+		if len(ids) > 0 {
+			var server ServerResult
+			if entry.Operation == "register" {
+				server = callServer(ctx, policy, config, driver, err, ids, func(d Driver) error { return d.Register(ctx, ids) })
+				result.Register.Servers = append(result.Register.Servers, server)
+			} else {
+				server = unsupportedRemoval(callServer(ctx, policy, config, driver, err, ids,
+					func(d Driver) error { return d.UnregisterBatch(ctx, ids) }), ids)
+				result.Unregister.Servers = append(result.Unregister.Servers, server)
+			}
+			if server.Err != nil && retryable(server.Err) {
+				return false
+			}
+			if server.Err != nil {
+				fmt.Printf("[Error]Outbox entry %d (%s of %d ids) rejected by %s, moved to dead letters: %v\n",
+					entry.Seq, entry.Operation, len(ids), config.ServerURL, server.Err)
+				if err := r.Outbox.DeadLetter(config.ServerURL, entry.Seq); err != nil {
+					fmt.Printf("Error moving outbox entry %d for %s to dead letters: %v\n", entry.Seq, config.ServerURL, err)
+					return false
+				}
+				continue
+			}
+			if entry.Operation == "register" {
+				state.confirm(ids, nil)
+			} else {
+				state.confirm(nil, ids)
+			}
+		}
+		if err := r.Outbox.Remove(config.ServerURL, entry.Seq); err != nil {
+			fmt.Printf("Error removing outbox entry %d for %s: %v\n", entry.Seq, config.ServerURL, err)
+			return false
+		}
+	}
+	return true
+}
+
+// OutboxDepth 返回各 server 在 outbox 中待发送的操作数，未设置 Outbox 时返回 nil
+func (r *Reporter) OutboxDepth() map[string]int {
+	if r.Outbox == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	depth := make(map[string]int, len(r.servers))
+	for server := range r.servers {
+		depth[server] = r.Outbox.Depth(server)
+	}
+	return depth
+}
+
+func (r *Reporter) resyncReason(state *serverState, known bool) string {
+	switch {
+	case !known:
+		return ResyncInitial
+	case state.dirty:
+		return ResyncFailure
+	case r.FullResyncInterval > 0 && time.Since(state.lastFull) >= r.FullResyncInterval:
+		return ResyncInterval
+	}
+	return ""
+}
+
+// missingOnServer 检查支持列表的 server 上是否缺少已确认的标识，如 server 重启后内存中的注册表为空。
+// 按标识的位置前缀（城市至服务类型）比较 server 上的数量与 confirmed，数量不同时再逐个核对：
+// 同前缀下其他集群注册的标识只使数量不同，不触发全量同步。查询出错时返回 false，由本次上报的结果反映 server 的状态
+func missingOnServer(ctx context.Context, driver Driver, confirmed map[string]string) bool {
+	if _, ok := driver.(Lister); !ok || len(confirmed) == 0 {
+		return false
+	}
+
+	prefixes := map[string][]string{}
+	for _, id := range confirmed {
+		prefix := id
+		if len(id) >= apis.ResourceIDMinLength {
+			prefix = id[:31]
+		}
+		prefixes[prefix] = append(prefixes[prefix], id)
+	}
+	for prefix, ids := range prefixes {
+		_, total, err := listServer(ctx, driver, ListOptions{Prefix: prefix, Limit: 1})
+		if err != nil || total == len(ids) {
+			continue
+		}
+		resources, _, err := listServer(ctx, driver, ListOptions{Prefix: prefix})
+		if err != nil {
+			continue
+		}
+		registered := make(map[string]bool, len(resources))
+		for _, resource := range resources {
+			registered[resource.ID] = true
+		}
+		for _, id := range ids {
+			if !registered[id] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// delta 计算需要注册（新增或容量变化）及注销的标识，容量变化时同时注销原有标识
+func delta(acked, want map[string]string) (add, remove []string) {
+	for key, id := range want {
+		if old, ok := acked[key]; !ok || old != id {
+			add = append(add, id)
+			if ok {
+				remove = append(remove, old)
+			}
+		}
+	}
+	for key, id := range acked {
+		if _, ok := want[key]; !ok {
+			remove = append(remove, id)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+func dedupe(ids []string) []string {
+	sort.Strings(ids)
+	out := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,384 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+// fakeServer 模拟 resource-server 的注册、批量注销及统计接口，并记录收到的请求
+type fakeServer struct {
+	mutex      sync.Mutex
+	resources  map[string]string
+	registered [][]string
+	removed    [][]string
+	// down 为 true 时所有请求返回 503
+	down bool
+	// rejectRegister 为 true 时注册请求返回 400
+	rejectRegister bool
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var body RequestBody
+	switch r.URL.Path {
+	case "/resources":
+		if s.rejectRegister {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.registered = append(s.registered, body.ComputeIDs)
+		for _, id := range body.ComputeIDs {
+			s.resources[apis.ResourceKey(id)] = id
+		}
+		w.WriteHeader(http.StatusCreated)
+	case "/v1/resources/unregister":
+		json.NewDecoder(r.Body).Decode(&body)
+		s.removed = append(s.removed, body.ComputeIDs)
+		for _, id := range body.ComputeIDs {
+			if s.resources[apis.ResourceKey(id)] == id {
+				delete(s.resources, apis.ResourceKey(id))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]int{"removed": len(body.ComputeIDs)})
+	case "/v1/resources":
+		var items []*apis.NodeResourceInfo
+		for _, id := range s.resources {
+			if strings.HasPrefix(id, r.URL.Query().Get("prefix")) {
+				items = append(items, apis.ParseResourceInfo(id))
+			}
+		}
+		total := len(items)
+		if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > 0 && limit < total {
+			items = items[:limit]
+		}
+		json.NewEncoder(w).Encode(apis.ResourceList{Items: items, Total: total})
+	case "/v1/stats":
+		json.NewEncoder(w).Encode(apis.RegistryStats{Resources: len(s.resources)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeServer) requests() ([][]string, [][]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	registered, removed := s.registered, s.removed
+	s.registered, s.removed = nil, nil
+	return registered, removed
+}
+
+func testComputeID(chip int, tflops int) string {
+	return fmt.Sprintf("1101tc2000140150102602001601004F%04dS0010000N010000P00400%02d%034d0000000000001%05d",
+		tflops, 1, 1, chip)
+}
+
+func TestReporterSendsOnlyChanges(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	serverConfigs = []ServerConfig{{ServerURL: srv.URL, Type: ServerTypeLocal}}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	reporter := NewReporter(time.Hour)
+	a, b, c := testComputeID(1, 100), testComputeID(2, 100), testComputeID(3, 100)
+
+	report := reporter.Report(context.Background(), []string{a, b}, nil)
+	if report.FullResync[srv.URL] != ResyncInitial {
+		t.Fatalf("expected initial full resync, got %v", report.FullResync)
+	}
+	fake.requests()
+
+	// 无变化时不发送请求
+	reporter.Report(context.Background(), []string{a, b}, nil)
+	if registered, removed := fake.requests(); len(registered) != 0 || len(removed) != 0 {
+		t.Fatalf("expected no requests, got %v %v", registered, removed)
+	}
+
+	// b 容量变化、a 移除、c 新增
+	b2 := testComputeID(2, 200)
+	report = reporter.Report(context.Background(), []string{b2, c}, nil)
+	if err := report.Register.Err(); err != nil {
+		t.Fatal(err)
+	}
+	registered, removed := fake.requests()
+	expectedAdd, expectedRemove := []string{b2, c}, []string{a, b}
+	sort.Strings(expectedAdd)
+	sort.Strings(expectedRemove)
+	if !reflect.DeepEqual(registered, [][]string{expectedAdd}) || !reflect.DeepEqual(removed, [][]string{expectedRemove}) {
+		t.Fatalf("unexpected delta: registered %v, removed %v", registered, removed)
+	}
+
+	// 同前缀下其他集群注册的标识不触发全量同步
+	other := testComputeID(9, 100)
+	other = other[:59] + strings.Repeat("0", 33) + "2" + other[93:]
+	fake.mutex.Lock()
+	fake.resources[apis.ResourceKey(other)] = other
+	fake.mutex.Unlock()
+	if report = reporter.Report(context.Background(), []string{b2, c}, nil); len(report.FullResync) != 0 {
+		t.Fatalf("unexpected full resync %v", report.FullResync)
+	}
+
+	// server 重启丢失注册表后全量同步
+	fake.mutex.Lock()
+	fake.resources = map[string]string{apis.ResourceKey(other): other}
+	fake.mutex.Unlock()
+	fake.requests()
+	report = reporter.Report(context.Background(), []string{b2, c}, nil)
+	if report.FullResync[srv.URL] != ResyncMismatch {
+		t.Fatalf("expected mismatch resync, got %v", report.FullResync)
+	}
+	if registered, _ := fake.requests(); len(registered) != 1 || len(registered[0]) != 2 {
+		t.Fatalf("expected full re-registration, got %v", registered)
+	}
+
+	// 定期全量同步
+	reporter.FullResyncInterval = time.Nanosecond
+	report = reporter.Report(context.Background(), []string{b2, c}, nil)
+	if report.FullResync[srv.URL] != ResyncInterval {
+		t.Fatalf("expected interval resync, got %v", report.FullResync)
+	}
+	if registered, _ := fake.requests(); len(registered) != 1 || len(registered[0]) != 2 {
+		t.Fatalf("expected full re-registration, got %v", registered)
+	}
+}
+
+func TestReporterReplaysOutbox(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	serverConfigs = []ServerConfig{{ServerURL: srv.URL, Type: ServerTypeLocal}}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	outbox, err := NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := NewReporter(time.Hour)
+	reporter.Outbox = outbox
+	a, b, c := testComputeID(1, 100), testComputeID(2, 100), testComputeID(3, 100)
+	reporter.Report(context.Background(), []string{a, b}, nil)
+
+	// 服务端不可达期间的变化依次排入 outbox
+	fake.mutex.Lock()
+	fake.down = true
+	fake.mutex.Unlock()
+	b2 := testComputeID(2, 200)
+	reporter.Report(context.Background(), []string{b2, c}, nil)
+	reporter.Report(context.Background(), []string{c}, nil)
+	if depth := reporter.OutboxDepth()[srv.URL]; depth != 3 {
+		t.Fatalf("expected 3 queued operations, got %d", depth)
+	}
+	// 排入 outbox 的标识尚未被 server 接收
+	if reporter.Acknowledged(srv.URL, []string{c}) {
+		t.Fatal("queued id reported as acknowledged")
+	}
+
+	// 恢复后按顺序重放，b2 的注册被随后的注销抵消
+	fake.mutex.Lock()
+	fake.down = false
+	fake.mutex.Unlock()
+	fake.requests()
+	report := reporter.Report(context.Background(), []string{c}, nil)
+	if err := report.Register.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if depth := reporter.OutboxDepth()[srv.URL]; depth != 0 {
+		t.Fatalf("expected empty outbox, got %d", depth)
+	}
+	if registered, _ := fake.requests(); !reflect.DeepEqual(registered, [][]string{{c}}) {
+		t.Fatalf("expected only %s to be registered, got %v", c, registered)
+	}
+	if !reporter.Acknowledged(srv.URL, []string{c}) || reporter.Acknowledged(srv.URL, []string{a}) {
+		t.Fatal("acknowledged ids do not match the ids delivered from the outbox")
+	}
+	if !reflect.DeepEqual(fake.resources, map[string]string{apis.ResourceKey(c): c}) {
+		t.Fatalf("unexpected server state %v", fake.resources)
+	}
+}
+
+func TestReporterDeadLettersRejectedOutboxEntries(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{}, down: true}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	serverConfigs = []ServerConfig{{ServerURL: srv.URL, Type: ServerTypeLocal}}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	dir := t.TempDir()
+	outbox, err := NewOutbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := NewReporter(time.Hour)
+	reporter.Outbox = outbox
+	a := testComputeID(1, 100)
+	reporter.Report(context.Background(), []string{a}, nil)
+
+	// 被拒绝的注册不阻塞后续的注销
+	fake.mutex.Lock()
+	fake.down, fake.rejectRegister = false, true
+	fake.mutex.Unlock()
+	report := reporter.Report(context.Background(), nil, nil)
+	if err := report.Unregister.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, removed := fake.requests(); !reflect.DeepEqual(removed, [][]string{{a}}) {
+		t.Fatalf("expected %s to be unregistered, got %v", a, removed)
+	}
+	if depth := reporter.OutboxDepth()[srv.URL]; depth != 0 {
+		t.Fatalf("expected empty outbox, got %d", depth)
+	}
+	dead, err := filepath.Glob(filepath.Join(outbox.serverDir(srv.URL), outboxDeadDir, "*"+outboxFileSuffix))
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("expected one dead letter, got %v: %v", dead, err)
+	}
+}
+
+func TestOutboxCompact(t *testing.T) {
+	outbox, err := NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, b2 := testComputeID(1, 100), testComputeID(2, 100), testComputeID(2, 200)
+	for _, op := range []struct {
+		operation string
+		ids       []string
+	}{{"register", []string{a, b}}, {"register", []string{b2}}, {"unregister", []string{a}}} {
+		if err := outbox.Enqueue("server", op.operation, op.ids); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dropped, err := outbox.Compact("server"); err != nil || dropped != 0 {
+		t.Fatalf("compact dropped %d: %v", dropped, err)
+	}
+	pending, err := outbox.Pending("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一个注册被覆盖后不含标识，已删除
+	var got [][]string
+	for _, entry := range pending {
+		got = append(got, entry.ComputeIDs)
+	}
+	if !reflect.DeepEqual(got, [][]string{{b2}, {a}}) {
+		t.Fatalf("unexpected compacted outbox %v", got)
+	}
+}
+
+func TestReporterTeardownBypassesOutbox(t *testing.T) {
+	fake := &fakeServer{resources: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	serverConfigs = []ServerConfig{{ServerURL: srv.URL, Type: ServerTypeLocal}}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	outbox, err := NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reporter := NewReporter(time.Hour)
+	reporter.Outbox = outbox
+	a, b := testComputeID(1, 100), testComputeID(2, 100)
+	reporter.Report(context.Background(), []string{a}, nil)
+	fake.mutex.Lock()
+	fake.down = true
+	fake.mutex.Unlock()
+	reporter.Report(context.Background(), []string{a, b}, nil)
+
+	// 不可达时返回错误，不排入 outbox
+	if result := reporter.Teardown(context.Background(), nil); result.Err() == nil {
+		t.Fatal("teardown reported success while the server is down")
+	}
+	if depth := reporter.OutboxDepth()[srv.URL]; depth != 1 {
+		t.Fatalf("expected the queued registration to be kept, got depth %d", depth)
+	}
+
+	// 恢复后同步注销已确认及积压的标识，并清空 outbox
+	fake.mutex.Lock()
+	fake.down = false
+	fake.mutex.Unlock()
+	fake.requests()
+	if result := reporter.Teardown(context.Background(), nil); result.Err() != nil {
+		t.Fatal(result.Err())
+	}
+	if _, removed := fake.requests(); !reflect.DeepEqual(removed, [][]string{{a, b}}) {
+		t.Fatalf("expected %s and %s to be unregistered, got %v", a, b, removed)
+	}
+	if pending, _ := outbox.Pending(srv.URL); len(pending) != 0 {
+		t.Fatalf("outbox not cleared after teardown: %v", pending)
+	}
+}
+
+func TestReporterUnsupportedUnregisterFails(t *testing.T) {
+	var registered [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body RequestBody
+		json.NewDecoder(r.Body).Decode(&body)
+		registered = append(registered, body.ComputeIDs)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "message": "ok"})
+	}))
+	defer srv.Close()
+
+	serverConfigs = []ServerConfig{{ServerURL: srv.URL + "/", Type: ServerTypeRemote}}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+
+	reporter := NewReporter(time.Hour)
+	a, b := testComputeID(1, 100), testComputeID(2, 100)
+	if report := reporter.Report(context.Background(), []string{a, b}, nil); report.Register.Err() != nil {
+		t.Fatal(report.Register.Err())
+	}
+
+	// 平台不支持注销：移除的标识仍留在平台上，上报失败且之后每次上报都会再次报告
+	for i := 0; i < 2; i++ {
+		report := reporter.Report(context.Background(), []string{a}, nil)
+		failed := report.Unregister.Failed()
+		if len(failed) != 1 || !errors.Is(failed[0].Err, ErrNotSupported) || failed[0].Skipped {
+			t.Fatalf("report %d: expected an unsupported unregister failure, got %+v", i, report.Unregister.Servers)
+		}
+	}
+	if statuses := reporter.ServerStatuses(); len(statuses) != 1 || !errors.Is(statuses[0].LastError, ErrNotSupported) {
+		t.Fatalf("unexpected server statuses %+v", statuses)
+	}
+
+	// 集群移除时同样返回错误，不清除已确认的标识
+	if failed := reporter.Teardown(context.Background(), nil).Failed(); len(failed) != 1 || !errors.Is(failed[0].Err, ErrNotSupported) {
+		t.Fatalf("expected teardown to fail, got %+v", failed)
+	}
+	if !reporter.Acknowledged(srv.URL+"/", []string{a, b}) {
+		t.Fatal("removals skipped by the platform were treated as acknowledged")
+	}
+	if len(registered) != 1 {
+		t.Fatalf("expected a single registration, got %v", registered)
+	}
+}
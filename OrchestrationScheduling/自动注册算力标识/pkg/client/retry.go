@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 为向每个 server 发送请求时的重试及熔断策略
+type RetryPolicy struct {
+	// MaxAttempts 为包含首次请求在内的最大尝试次数
+	MaxAttempts int
+	// InitialBackoff 为首次重试前的等待时间，之后按 Multiplier 倍增，不超过 MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter 为等待时间的随机抖动比例，0.2 表示在 ±20% 范围内随机
+	Jitter float64
+
+	// BreakerThreshold 为连续失败多少次后熔断该 server，0 表示不熔断
+	BreakerThreshold int
+	// BreakerCooldown 为熔断持续时间，之后放行一次试探请求
+	BreakerCooldown time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      4,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+		Multiplier:       2,
+		Jitter:           0.2,
+		BreakerThreshold: 5,
+		BreakerCooldown:  60 * time.Second,
+	}
+}
+
+var (
+	retryPolicy = DefaultRetryPolicy()
+	breakers    = map[string]*circuitBreaker{}
+	breakerLock sync.Mutex
+)
+
+// SetRetryPolicy 设置 RegisterResource、UnregisterResource 等函数使用的重试及熔断策略
+func SetRetryPolicy(policy RetryPolicy) {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	retryPolicy = policy
+	breakers = map[string]*circuitBreaker{}
+}
+
+func currentRetryPolicy() RetryPolicy {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	return retryPolicy
+}
+
+// ErrCircuitOpen 表示该 server 连续失败已被熔断，请求未发送
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker 按 server 统计连续失败次数，超过阈值后在冷却期内拒绝请求
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func breakerFor(serverURL string) *circuitBreaker {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	b, ok := breakers[serverURL]
+	if !ok {
+		b = &circuitBreaker{}
+		breakers[serverURL] = b
+	}
+	return b
+}
+
+// CircuitOpen 返回 server 当前是否处于熔断状态
+func CircuitOpen(serverURL string) bool {
+	b := breakerFor(serverURL)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return time.Now().Before(b.openUntil)
+}
+
+// allow 判断是否可以发送请求，冷却期结束后只放行一个试探请求
+func (b *circuitBreaker) allow(policy RetryPolicy) bool {
+	if policy.BreakerThreshold <= 0 {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < policy.BreakerThreshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record 记录一次操作的最终结果。不可重试的错误（如 4xx）说明 server 可达，不计入连续失败
+func (b *circuitBreaker) record(policy RetryPolicy, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if err == nil || !retryable(err) {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if policy.BreakerThreshold > 0 && b.failures >= policy.BreakerThreshold {
+		b.openUntil = time.Now().Add(policy.BreakerCooldown)
+	}
+}
+
+// retryable 判断错误是否值得重试：网络错误、429 及 5xx 可重试，其余 4xx 及业务错误不重试
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var codeErr *remoteCodeError
+	return !errors.As(err, &codeErr) && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNotSupported)
+}
+
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	d := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= p.Multiplier
+	}
+	if max := float64(p.MaxBackoff); p.MaxBackoff > 0 && d > max {
+		d = max
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// withRetry 按策略调用 fn，返回尝试次数及最后一次的错误；熔断时不发送请求。
+// 熔断器按操作计数，重试耗尽后才记录一次失败
+func withRetry(ctx context.Context, serverURL string, policy RetryPolicy, fn func() error) (int, error) {
+	breaker := breakerFor(serverURL)
+	if !breaker.allow(policy) {
+		return 0, ErrCircuitOpen
+	}
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err) || attempt == attempts {
+			breaker.record(policy, err)
+			return attempt, err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			breaker.record(policy, err)
+			return attempt, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ServerResult 为一次操作在单个 server 上的结果
+type ServerResult struct {
+	ServerURL string
+	Type      string
+	// Accepted 为该 server 接受的算力标识
+	Accepted []string
+	Attempts int
+	Err      error
+	// Skipped 为 true 表示该服务类型不支持此操作，没有发送请求
+	Skipped bool
+}
+
+// RegistrationResult 汇总一次注册或注销在各 server 上的结果
+type RegistrationResult struct {
+	Operation string
+	Servers   []ServerResult
+}
+
+func (r RegistrationResult) Failed() []ServerResult {
+	var failed []ServerResult
+	for _, server := range r.Servers {
+		if server.Err != nil {
+			failed = append(failed, server)
+		}
+	}
+	return failed
+}
+
+// Err 在任一 server 失败时返回汇总的错误
+func (r RegistrationResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(failed))
+	for _, server := range failed {
+		messages = append(messages, fmt.Sprintf("%s: %v", server.ServerURL, server.Err))
+	}
+	return fmt.Errorf("%s failed on %d of %d servers: %s", r.Operation, len(failed), len(r.Servers),
+		strings.Join(messages, "; "))
+}
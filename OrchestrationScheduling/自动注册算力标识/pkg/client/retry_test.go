@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if got := policy.backoff(attempt, errors.New("connection refused")); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+
+	// 429 按 Retry-After 等待
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second}
+	if got := policy.backoff(1, throttled); got != 3*time.Second {
+		t.Fatalf("Retry-After not honored: %v", got)
+	}
+
+	policy.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(1, nil); got < 80*time.Millisecond || got > 120*time.Millisecond {
+			t.Fatalf("jittered backoff %v out of range", got)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection refused"), true},
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{fmt.Errorf("register: %w", &APIError{StatusCode: http.StatusInternalServerError}), true},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{&APIError{StatusCode: http.StatusNotFound}, false},
+		{&remoteCodeError{Code: 1, Message: "invalid compute id"}, false},
+		{ErrNotSupported, false},
+	} {
+		if got := retryable(tc.err); got != tc.want {
+			t.Errorf("retryable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestWithRetryCircuitBreaker(t *testing.T) {
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	defer SetRetryPolicy(DefaultRetryPolicy())
+	policy := currentRetryPolicy()
+	const server = "http://breaker.test"
+
+	calls := 0
+	call := func(err error) (int, error) {
+		return withRetry(context.Background(), server, policy, func() error {
+			calls++
+			return err
+		})
+	}
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	// 一次操作的多次重试只计一次失败
+	if attempts, err := call(unavailable); attempts != 3 || err != unavailable || CircuitOpen(server) {
+		t.Fatalf("first operation: %d attempts, %v, open %v", attempts, err, CircuitOpen(server))
+	}
+	// 不可重试的错误不重试，也不计入失败
+	if attempts, _ := call(&APIError{StatusCode: http.StatusBadRequest}); attempts != 1 || CircuitOpen(server) {
+		t.Fatalf("bad request: %d attempts, open %v", attempts, CircuitOpen(server))
+	}
+	call(unavailable)
+	call(unavailable)
+	if !CircuitOpen(server) {
+		t.Fatal("circuit not open after two failed operations")
+	}
+
+	calls = 0
+	if attempts, err := call(nil); attempts != 0 || err != ErrCircuitOpen || calls != 0 {
+		t.Fatalf("open circuit: %d attempts, %v, %d calls", attempts, err, calls)
+	}
+
+	// 冷却结束后放行试探请求，成功后恢复
+	time.Sleep(60 * time.Millisecond)
+	if _, err := call(nil); err != nil || CircuitOpen(server) || calls != 1 {
+		t.Fatalf("probe: %v, open %v, %d calls", err, CircuitOpen(server), calls)
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// ExportSnapshot 从本地算力资源展示服务导出注册表快照，format 为 jsonl 或 csv
+func ExportSnapshot(format string, out io.Writer) error {
+	config, err := localServerConfig()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {format}}
+	resp, err := http.Get(config.ServerURL + "/v1/snapshot?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("error exporting snapshot: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("error exporting snapshot: status code %d: %s", resp.StatusCode, body)
+	}
+
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// ImportSnapshot 将快照导入本地算力资源展示服务，mode 为 merge 或 replace
+func ImportSnapshot(in io.Reader, format, mode string) error {
+	config, err := localServerConfig()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"format": {format}, "mode": {mode}}
+	req, err := http.NewRequest("POST", config.ServerURL+"/v1/snapshot?"+query.Encode(), in)
+	if err != nil {
+		return fmt.Errorf("error creating import request: %v", err)
+	}
+	if format == "csv" {
+		req.Header.Set("Content-Type", "text/csv")
+	} else {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error importing snapshot: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error importing snapshot: status code %d: %s", resp.StatusCode, body)
+	}
+
+	fmt.Println("Imported:", string(body))
+	return nil
+}
+
+func localServerConfig() (ServerConfig, error) {
+	for _, config := range serverConfigs {
+		if config.Type == "local" {
+			return config, nil
+		}
+	}
+	return ServerConfig{}, fmt.Errorf("no local server configured")
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// TLSConfig 为访问 server 的 TLS 配置。客户端证书的 CN 即 server 限流、配额及租户隔离使用的调用方身份；
+// 证书及私钥在每次建立连接时从文件读取，Secret 轮换后无需重启
+type TLSConfig struct {
+	// CAFile 为校验 server 证书的 CA，为空时使用系统 CA
+	CAFile   string `json:"caFile,omitempty"`
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+}
+
+func (c *TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("tls certFile and keyFile must be set together")
+	}
+	return nil
+}
+
+var (
+	transportMutex sync.Mutex
+	// transports 按 TLS 配置缓存传输，复用连接
+	transports = map[TLSConfig]http.RoundTripper{}
+)
+
+// serverTransport 返回访问 server 的底层传输，未配置 TLS 时为 http.DefaultTransport
+func serverTransport(config ServerConfig) (http.RoundTripper, error) {
+	if config.TLS == nil {
+		return http.DefaultTransport, nil
+	}
+
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+	if transport, ok := transports[*config.TLS]; ok {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLS.CAFile != "" {
+		data, err := ioutil.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading tls caFile: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in tls caFile %s", config.TLS.CAFile)
+		}
+	}
+	if certFile, keyFile := config.TLS.CertFile, config.TLS.KeyFile; certFile != "" {
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("error loading client certificate: %v", err)
+			}
+			return &cert, nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transports[*config.TLS] = transport
+	return transport, nil
+}
@@ -1,32 +1,71 @@
 package client
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"os"
 )
 
-func UnregisterResource(id string) {
-	for _, config := range serverConfigs {
-		if config.Type == "local" {
-			req, err := http.NewRequest("DELETE", config.ServerURL+"/resources/"+id, nil)
-			if err != nil {
-				fmt.Println("Error unregistering resource:", err)
-				return
-			}
+// UnregisterResource 从 config.json 中的每个 server 注销算力标识，失败时按重试策略重试
+func UnregisterResource(id string) RegistrationResult {
+	result := callServers(context.Background(), "unregister", []string{id}, func(driver Driver) error {
+		return driver.Unregister(context.Background(), id)
+	})
+	for _, server := range result.Servers {
+		if server.Skipped {
+			fmt.Fprintf(os.Stderr, "Unregister is not supported by %s (%s), skipped\n", server.ServerURL, server.Type)
+		} else if server.Err == nil {
+			fmt.Printf("[Info]Resource unregistered successfully from %s\n", server.ServerURL)
+		}
+	}
+	return result
+}
 
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				fmt.Println("Error unregistering resource:", err)
-				return
-			}
-			defer resp.Body.Close()
+// UnregisterResources 从 config.json 中的每个 server 批量注销算力标识
+func UnregisterResources(ids []string) RegistrationResult {
+	result := callServers(context.Background(), "unregister", ids, func(driver Driver) error {
+		return driver.UnregisterBatch(context.Background(), ids)
+	})
+	for _, server := range result.Servers {
+		if server.Skipped {
+			fmt.Fprintf(os.Stderr, "Unregister is not supported by %s (%s), skipped\n", server.ServerURL, server.Type)
+		} else if server.Err == nil {
+			fmt.Printf("Unregistered %d resources from %s\n", len(server.Accepted), server.ServerURL)
+		}
+	}
+	return result
+}
 
-			if resp.StatusCode != http.StatusNoContent {
-				fmt.Println("Error unregistering resource: status code", resp.StatusCode)
-				return
+// UnregisterMatching 注销每个 server 上与 options 的前缀及选择器匹配的全部算力标识，忽略分页参数
+func UnregisterMatching(options ListOptions) RegistrationResult {
+	options.Offset, options.Limit = 0, 0
+	policy := currentRetryPolicy()
+	result := RegistrationResult{Operation: "unregister"}
+	for _, config := range serverConfigs {
+		driver, err := NewDriver(config)
+		var ids []string
+		if err == nil {
+			resources, _, listErr := listServer(context.Background(), driver, options)
+			for _, resource := range resources {
+				ids = append(ids, resource.ID)
 			}
+			err = listErr
+		}
+		if err == nil && len(ids) == 0 {
+			fmt.Fprintf(os.Stderr, "No matching resources on %s\n", config.ServerURL)
+			result.Servers = append(result.Servers, ServerResult{ServerURL: config.ServerURL, Type: config.Type})
+			continue
+		}
 
-			fmt.Println("[Debug]Resource unregistered successfully")
+		server := callServer(context.Background(), policy, config, driver, err, ids, func(d Driver) error {
+			return d.UnregisterBatch(context.Background(), ids)
+		})
+		if server.Skipped {
+			fmt.Fprintf(os.Stderr, "Unregister is not supported by %s (%s), skipped\n", server.ServerURL, server.Type)
+		} else if server.Err == nil {
+			fmt.Printf("Unregistered %d resources from %s\n", len(server.Accepted), server.ServerURL)
 		}
+		result.Servers = append(result.Servers, server)
 	}
+	return result
 }
@@ -0,0 +1,262 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// 集群控制器状态
+const (
+	ClusterStarting = "starting"
+	ClusterRunning  = "running"
+	ClusterFailed   = "failed"
+)
+
+// teardownTimeout 为集群移除时注销其算力标识的最长时间
+const teardownTimeout = 2 * time.Minute
+
+// ClusterStatus 为 /healthz/clusters 返回的单个集群状态
+type ClusterStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// KubeconfigHash 为当前 kubeconfig 的 SHA-256 前 12 位，便于确认轮换是否生效
+	KubeconfigHash string    `json:"kubeconfigHash"`
+	StartedAt      time.Time `json:"startedAt"`
+	Error          string    `json:"error,omitempty"`
+	ControllerStatus
+}
+
+// ClusterManager 按 kubeconfig 来源（如 ConfigMap 名称）管理各成员集群的控制器：
+// kubeconfig 变化时停止旧控制器后重启，来源删除时停止控制器并注销该集群上报过的算力标识
+type ClusterManager struct {
+	options      *Options
+	labelWatcher *LabelWatcher
+
+	mutex    sync.Mutex
+	clusters map[string]*clusterRunner
+	// stopped 在 Stop 后为 true，不再启动集群控制器
+	stopped bool
+}
+
+// clusterRunner 为一个运行中的集群控制器，done 在 Run 返回后关闭
+type clusterRunner struct {
+	name       string
+	hash       string
+	startedAt  time.Time
+	controller *NodeResourceController
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	mutex sync.Mutex
+	state string
+	err   error
+}
+
+// NewClusterManager 创建 ClusterManager，labelWatcher 可为 nil；label-config 变化时各集群重新协调全部节点
+func NewClusterManager(options *Options, labelWatcher *LabelWatcher) *ClusterManager {
+	m := &ClusterManager{options: options, labelWatcher: labelWatcher, clusters: map[string]*clusterRunner{}}
+	if labelWatcher != nil {
+		labelWatcher.OnChange(m.resyncNodes)
+	}
+	return m
+}
+
+func kubeconfigHash(kubeconfig []byte) string {
+	sum := sha256.Sum256(kubeconfig)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Apply 使集群 name 以 kubeconfig 运行，kubeconfig 未变化时不做任何操作
+func (m *ClusterManager) Apply(name string, kubeconfig []byte) {
+	hash := kubeconfigHash(kubeconfig)
+
+	m.mutex.Lock()
+	old, ok := m.clusters[name]
+	if m.stopped || (ok && old.hash == hash) {
+		m.mutex.Unlock()
+		return
+	}
+	delete(m.clusters, name)
+	m.mutex.Unlock()
+
+	// 在锁外等待旧控制器退出，期间不阻塞 Statuses 等调用；Apply 及 Remove 只由单个 worker 调用，不会并发处理同一集群
+	if ok {
+		fmt.Printf("[Info]Kubeconfig of cluster %s changed, restarting its controller\n", name)
+		old.stop()
+	}
+
+	runner := m.newRunner(name, hash, kubeconfig)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped {
+		return
+	}
+	m.clusters[name] = runner
+	if runner.controller != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		runner.cancel = cancel
+		go runner.run(ctx)
+	}
+}
+
+// newRunner 按 kubeconfig 创建集群控制器但不启动，kubeconfig 无效时返回 failed 状态的 runner
+func (m *ClusterManager) newRunner(name, hash string, kubeconfig []byte) *clusterRunner {
+	runner := &clusterRunner{name: name, hash: hash, startedAt: time.Now(), state: ClusterStarting, done: make(chan struct{})}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		runner.fail(fmt.Errorf("failed to parse kubeconfig: %v", err))
+		return runner
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		runner.fail(fmt.Errorf("failed to create Kubernetes client: %v", err))
+		return runner
+	}
+
+	var dynamicClient dynamic.Interface
+	if m.options.ComputeRegistrations {
+		if dynamicClient, err = dynamic.NewForConfig(config); err != nil {
+			runner.fail(fmt.Errorf("failed to create dynamic client: %v", err))
+			return runner
+		}
+	}
+
+	runner.controller = NewNodeResourceController(name, clientset, dynamicClient, m.options, m.labelWatcher)
+	return runner
+}
+
+// Remove 停止集群 name 的控制器并注销其上报过的算力标识
+func (m *ClusterManager) Remove(name string) {
+	m.mutex.Lock()
+	runner, ok := m.clusters[name]
+	delete(m.clusters, name)
+	m.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	runner.stop()
+	defer forgetCluster(name)
+	if runner.controller == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+	defer cancel()
+	if err := runner.controller.Teardown(ctx); err != nil {
+		fmt.Printf("[Error]Failed to unregister resources of removed cluster %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("[Info]Cluster %s removed and its resources unregistered\n", name)
+}
+
+// Stop 停止全部集群控制器且不注销算力标识，用于失去领导权后交由新的 leader 继续上报
+func (m *ClusterManager) Stop() {
+	m.mutex.Lock()
+	m.stopped = true
+	runners := m.clusters
+	m.clusters = map[string]*clusterRunner{}
+	m.mutex.Unlock()
+
+	for _, runner := range runners {
+		runner.stop()
+	}
+}
+
+func (m *ClusterManager) resyncNodes() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, runner := range m.clusters {
+		if runner.controller != nil {
+			runner.controller.resyncNodes()
+		}
+	}
+}
+
+// Statuses 返回各集群的状态，按名称排序
+func (m *ClusterManager) Statuses() []ClusterStatus {
+	m.mutex.Lock()
+	runners := make([]*clusterRunner, 0, len(m.clusters))
+	for _, runner := range m.clusters {
+		runners = append(runners, runner)
+	}
+	m.mutex.Unlock()
+
+	statuses := make([]ClusterStatus, 0, len(runners))
+	for _, runner := range runners {
+		statuses = append(statuses, runner.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// ServeHTTP 提供 /healthz/clusters，任一集群失败时返回 503
+func (m *ClusterManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := m.Statuses()
+	status := http.StatusOK
+	for _, cluster := range statuses {
+		if cluster.State == ClusterFailed {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (r *clusterRunner) run(ctx context.Context) {
+	defer close(r.done)
+	r.setState(ClusterRunning, nil)
+	if err := r.controller.Run(ctx); err != nil {
+		r.fail(err)
+	}
+}
+
+// stop 取消控制器并等待其退出
+func (r *clusterRunner) stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *clusterRunner) fail(err error) {
+	fmt.Printf("[Error]Cluster %s: %v\n", r.name, err)
+	r.setState(ClusterFailed, err)
+}
+
+func (r *clusterRunner) setState(state string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.state, r.err = state, err
+}
+
+func (r *clusterRunner) status() ClusterStatus {
+	r.mutex.Lock()
+	status := ClusterStatus{Name: r.name, State: r.state, KubeconfigHash: r.hash, StartedAt: r.startedAt}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+	r.mutex.Unlock()
+
+	if r.controller != nil {
+		status.ControllerStatus = r.controller.Status()
+		if status.State == ClusterRunning && !status.Synced {
+			status.State = ClusterStarting
+		}
+	}
+	return status
+}
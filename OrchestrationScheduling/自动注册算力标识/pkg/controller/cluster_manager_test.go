@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeAPIServer 只提供节点的 list 及 watch，watch 请求一直保持到连接关闭
+func fakeAPIServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"kind":"NodeList","apiVersion":"v1","metadata":{"resourceVersion":"1"},"items":[]}`)
+	}))
+}
+
+func testKubeconfig(server, user string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: member
+  cluster:
+    server: %s
+users:
+- name: %s
+  user:
+    token: test
+contexts:
+- name: member
+  context:
+    cluster: member
+    user: %s
+current-context: member
+`, server, user, user))
+}
+
+func waitForState(t *testing.T, m *ClusterManager, check func(statuses []ClusterStatus) bool) []ClusterStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := m.Statuses()
+		if check(statuses) {
+			return statuses
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected cluster statuses %+v", statuses)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClusterManagerLifecycle(t *testing.T) {
+	apiServer := fakeAPIServer()
+	defer apiServer.Close()
+
+	m := NewClusterManager(NewOptions(), nil)
+	running := func(statuses []ClusterStatus) bool {
+		return len(statuses) == 1 && statuses[0].State == ClusterRunning
+	}
+
+	m.Apply("member", testKubeconfig(apiServer.URL, "a"))
+	first := waitForState(t, m, running)[0]
+
+	// kubeconfig 未变化时不重启
+	m.Apply("member", testKubeconfig(apiServer.URL, "a"))
+	if status := m.Statuses()[0]; !status.StartedAt.Equal(first.StartedAt) {
+		t.Fatalf("controller restarted although kubeconfig is unchanged")
+	}
+
+	// kubeconfig 变化后重启
+	m.Apply("member", testKubeconfig(apiServer.URL, "b"))
+	second := waitForState(t, m, running)[0]
+	if second.KubeconfigHash == first.KubeconfigHash || !second.StartedAt.After(first.StartedAt) {
+		t.Fatalf("controller not restarted after kubeconfig change: %+v %+v", first, second)
+	}
+
+	// 无效的 kubeconfig 标记为失败
+	m.Apply("broken", []byte("not a kubeconfig"))
+	waitForState(t, m, func(statuses []ClusterStatus) bool {
+		return len(statuses) == 2 && statuses[0].Name == "broken" && statuses[0].State == ClusterFailed
+	})
+
+	m.Remove("member")
+	m.Remove("broken")
+	waitForState(t, m, func(statuses []ClusterStatus) bool { return len(statuses) == 0 })
+}
@@ -2,13 +2,26 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 	"register-power-resources/pkg/apis"
 	"register-power-resources/pkg/client"
 )
@@ -39,155 +52,623 @@ const (
 	GPUPowerConsumption = "cncos.org/gpu-power-consumption"
 )
 
-type NodeResourceController struct {
-	clientset *kubernetes.Clientset
+// 静态字段的名称，节点上未设置编码标签时使用。可作为节点注解（可为中文名称，如 cncos.org/city-name=北京），
+// 或节点标签（标签值只能是 ASCII，如编码或 az1、hpc 等别名）
+const (
+	CityName         = "cncos.org/city-name"
+	CompanyTypeName  = "cncos.org/company-type-name"
+	CompanyName      = "cncos.org/company-name"
+	ResourceTypeName = "cncos.org/resource-type-name"
+	ResourceAZName   = "cncos.org/resource-az-name"
+	ServiceTypeName  = "cncos.org/service-type-name"
+)
+
+// staticField 为算力标识中的一个静态字段，取值优先级：节点的 ComputeRegistration > 编码标签 > 节点上的名称注解 >
+// 名称标签 > 集群的 ComputeRegistration > label-config 中的名称
+type staticField struct {
+	label, nameKey, configKey, specKey string
+	width                              int
+	resolve                            func(string) (string, error)
+	configName                         func(apis.LabelInfo) string
+	specValue                          func(*apis.ComputeRegistrationSpec) string
 }
 
-func NewNodeResourceController(clientset *kubernetes.Clientset) *NodeResourceController {
-	return &NodeResourceController{clientset: clientset}
+var staticFields = []staticField{
+	{City, CityName, "cityName", "city", 4, apis.ResolveCity,
+		func(info apis.LabelInfo) string { return info.CityName },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.City }},
+	{CompanyType, CompanyTypeName, "industryName", "industry", 2, apis.ResolveIndustry,
+		func(info apis.LabelInfo) string { return info.IndustryName },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.Industry }},
+	{Company, CompanyName, "enterpriseName", "enterprise", 5, apis.ResolveEnterprise,
+		func(info apis.LabelInfo) string { return info.EnterpriseName },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.Enterprise }},
+	{ResourceType, ResourceTypeName, "resourceType", "resourceType", 3, apis.ResolveResourceType,
+		func(info apis.LabelInfo) string { return info.ResourceType },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.ResourceType }},
+	{ResourceAZ, ResourceAZName, "dataCenterName", "dataCenter", 3, apis.ResolveDataCenter,
+		func(info apis.LabelInfo) string { return info.DataCenterName },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.DataCenter }},
+	{ServiceType, ServiceTypeName, "serviceType", "serviceType", 14, apis.ResolveServiceTypes,
+		func(info apis.LabelInfo) string { return info.ServiceType },
+		func(spec *apis.ComputeRegistrationSpec) string { return spec.ServiceType }},
 }
 
-func (c *NodeResourceController) Run() {
-	// 定期同步节点资源信息
-	ticker := time.NewTicker(60 * time.Second)
-	for range ticker.C {
-		c.syncNodeResources()
-	}
+// staticSources 为静态字段在节点标签之外的来源
+type staticSources struct {
+	// node、cluster 为节点及整个集群的 ComputeRegistration，可为 nil
+	node, cluster *apis.ComputeRegistration
+	labelInfo     apis.LabelInfo
 }
 
-func (c *NodeResourceController) syncNodeResources() {
-	//fmt.Println("[Debug]In syncNodeResources")
-	// 获取节点列表
-	nodes, err := c.clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+// code 返回节点上该字段的编码
+func (f staticField) code(node *corev1.Node, sources staticSources) (string, error) {
+	var source, name string
+	switch {
+	case sources.node != nil && f.specValue(&sources.node.Spec) != "":
+		source, name = "ComputeRegistration "+sources.node.Name+" spec."+f.specKey, f.specValue(&sources.node.Spec)
+	case node.Labels[f.label] != "":
+		code := node.Labels[f.label]
+		if len(code) != f.width {
+			return "", fmt.Errorf("label %s: expected %d characters, got %q", f.label, f.width, code)
+		}
+		return code, nil
+	case node.Annotations[f.nameKey] != "":
+		source, name = "annotation "+f.nameKey, node.Annotations[f.nameKey]
+	case node.Labels[f.nameKey] != "":
+		source, name = "label "+f.nameKey, node.Labels[f.nameKey]
+	case sources.cluster != nil && f.specValue(&sources.cluster.Spec) != "":
+		source, name = "ComputeRegistration "+sources.cluster.Name+" spec."+f.specKey, f.specValue(&sources.cluster.Spec)
+	case f.configName(sources.labelInfo) != "":
+		source, name = "label-config "+f.configKey, f.configName(sources.labelInfo)
+	default:
+		return "", fmt.Errorf("%s is not set, set label %s, annotation %s or %s in the label-config ConfigMap",
+			f.label, f.label, f.nameKey, f.configKey)
+	}
+	code, err := f.resolve(name)
 	if err != nil {
-		panic(err.Error())
+		return "", fmt.Errorf("%s: %v", source, err)
+	}
+	if len(code) != f.width {
+		return "", fmt.Errorf("%s: %q resolves to %q, expected %d characters", source, name, code, f.width)
+	}
+	return code, nil
+}
+
+// staticNamesChanged 判断节点上静态字段的名称注解是否变化，名称标签的变化已由标签比较覆盖
+func staticNamesChanged(oldNode, newNode *corev1.Node) bool {
+	for _, field := range staticFields {
+		if oldNode.Annotations[field.nameKey] != newNode.Annotations[field.nameKey] {
+			return true
+		}
 	}
+	return false
+}
 
-	// 遍历节点并收集资源信息
-	var registerDataMap []string
-	var unRegisterDataMap []string
-	var info apis.NodeResourceInfo
+// reportKey 为队列中触发上报的特殊键，节点名不会与之冲突
+const reportKey = "/report"
 
-	for _, node := range nodes.Items {
-		fmt.Println("Node [" + node.Name + "]: ")
+// reportDelay 为节点变化后等待合并的时间，期间的多次变化只上报一次
+const reportDelay = time.Second
 
-		if len(node.Labels[City]) != 4 || len(node.Labels[CompanyType]) != 2 ||
-			len(node.Labels[Company]) != 5 || len(node.Labels[ResourceType]) != 3 ||
-			len(node.Labels[ResourceAZ]) != 3 || len(node.Labels[ServiceType]) != 14 {
-			fmt.Println("[Info]Static Info not enough")
-			continue
-		}
+// NodeResourceController 通过 Node informer 感知节点标签变化，按节点在限速队列中协调：
+// 每个节点组装出的算力标识缓存在 nodes 中，有变化时合并上报，周期性 resync 时重新协调全部节点
+type NodeResourceController struct {
+	cluster      string
+	clientset    kubernetes.Interface
+	options      *Options
+	reporter     *client.Reporter
+	labelWatcher *LabelWatcher
+	// registrations 未启用 -compute-registrations 时为 nil
+	registrations *registrations
 
-		// 创建 NodeResourceInfo 结构
-		info = apis.NodeResourceInfo{
-			City:         fmt.Sprintf("%0*s", 4, node.Labels[City]),
-			CompanyType:  fmt.Sprintf("%0*s", 2, node.Labels[CompanyType]),
-			Company:      fmt.Sprintf("%0*s", 5, node.Labels[Company]),
-			ResourceType: fmt.Sprintf("%0*s", 3, node.Labels[ResourceType]),
-			ResourceAZ:   fmt.Sprintf("%0*s", 3, node.Labels[ResourceAZ]),
-			ServiceType:  fmt.Sprintf("%0*s", 14, node.Labels[ServiceType]),
+	informerFactory informers.SharedInformerFactory
+	nodeLister      corelisters.NodeLister
+	nodesSynced     cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
 
-			// 动态采集，包含计算量、存储、网络带宽、功耗
-			StorageCapacity:   fmt.Sprintf("S%0*s", 7, node.Labels[StorageCapacity]),
-			NetworkBandSwitch: fmt.Sprintf("N%0*s", 6, node.Labels[NetworkBandwidth]),
+	mutex sync.Mutex
+	nodes map[string]nodeResources
 
-			// 新增需求， 网络、芯片的属性通过动态探测的方式完成，这部分解耦通过 daemon set 来上报处理
-			NetworkType:          fmt.Sprintf("%0*s", 2, node.Labels[NetworkType]),
-			PowerResourceAddress: fmt.Sprintf("00" + fmt.Sprintf("%0*s", 32, node.Labels[NetworkAddress])),
-		}
+	statusMutex sync.Mutex
+	status      ControllerStatus
+}
 
-		//CPU 数据组装
-		chipNum, err := strconv.ParseInt(node.Labels[CPUChipNumber], 10, 64)
+// nodeResources 为单个节点按芯片类型分组的资源，register 为 false 的节点需要注销；
+// problems 为标签校验失败的原因，非空时 chips 为空
+type nodeResources struct {
+	register bool
+	chips    []chipGroup
+	problems []string
+}
+
+// chipGroup 为节点上同一类型、型号的芯片，info 中不含芯片唯一编号，由上报模式决定
+type chipGroup struct {
+	info  apis.NodeResourceInfo
+	count int64
+}
+
+// NewNodeResourceController 创建集群 cluster 的控制器，启用 outbox 时未送达的操作保存在 OutboxDir/cluster 下；
+// dynamicClient 用于读写 ComputeRegistration，为 nil 时不启用；labelWatcher 提供 label-config 中的名称，可为 nil
+func NewNodeResourceController(cluster string, clientset kubernetes.Interface, dynamicClient dynamic.Interface,
+	options *Options, labelWatcher *LabelWatcher) *NodeResourceController {
+	reporter := client.NewReporter(options.FullResyncInterval)
+	if options.OutboxDir != "" {
+		outbox, err := client.NewOutbox(filepath.Join(options.OutboxDir, cluster))
 		if err != nil {
-			fmt.Println(chipNum, err)
-			continue
+			fmt.Printf("Error: Failed to open outbox for cluster %s, undelivered operations will not be persisted: %v\n", cluster, err)
+		} else {
+			reporter.Outbox = outbox
 		}
+	}
+
+	// resync 周期即同步周期，informer 会定期对全部节点发出 Update 事件
+	informerFactory := informers.NewSharedInformerFactory(clientset, options.SyncInterval)
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	c := &NodeResourceController{
+		cluster:         cluster,
+		clientset:       clientset,
+		options:         options,
+		reporter:        reporter,
+		labelWatcher:    labelWatcher,
+		informerFactory: informerFactory,
+		nodeLister:      nodeInformer.Lister(),
+		nodesSynced:     nodeInformer.Informer().HasSynced,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nodes-"+cluster),
+		nodes:           map[string]nodeResources{},
+	}
 
-		var i int64
-		for i = 1; i <= chipNum; i++ {
-			info.ChipUniqNumber = fmt.Sprintf("%05d", i)
-			info.ChipType = fmt.Sprintf("%0*s", 5, node.Labels[CPUChipType])
-			info.ChipModel = fmt.Sprintf("%0*s", 8, node.Labels[CPUChipModel])
-			info.ComputeCapacity = fmt.Sprintf("F%0*s", 4, node.Labels[CPUComputeCapacity])
-			info.PowerConsumption = fmt.Sprintf("P%0*s", 5, node.Labels[CPUPowerConsumption])
-
-			// 城市>-行业>-企业>-资源类型>-数据中心>-服务类型>-计算、存储、网络及功耗>-网络类型>-算力互联网地址>-芯片类型>-芯片型号>-芯片唯一编号。
-			registerData := fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s",
-				info.City, info.CompanyType, info.Company, info.ResourceType, info.ResourceAZ, info.ServiceType,
-				info.ComputeCapacity, info.StorageCapacity, info.NetworkBandSwitch, info.PowerConsumption,
-				info.NetworkType, info.PowerResourceAddress, info.ChipType, info.ChipModel, info.ChipUniqNumber)
-
-			if len(node.Labels[Register]) == 0 || node.Labels[Register] != "true" {
-				fmt.Printf("[Info]Node %s need not register and unregister it first. If you want to register it, "+
-					"label it with cncos.org/register=true\n", node.Name)
-				unRegisterDataMap = append(unRegisterDataMap, registerData)
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueNode,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+			// 只关心标签、名称注解变化及周期性 resync（ResourceVersion 不变），忽略心跳等状态更新
+			if oldNode.ResourceVersion == newNode.ResourceVersion || !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+				staticNamesChanged(oldNode, newNode) {
+				c.enqueueNode(newObj)
+			}
+		},
+		DeleteFunc: c.enqueueNode,
+	})
+	if dynamicClient != nil {
+		c.registrations = newRegistrations(dynamicClient, options.SyncInterval, func(nodeName string) {
+			if nodeName == "" {
+				c.resyncNodes()
 			} else {
-				// 将节点资源信息发送到 Web 服务器
-				registerDataMap = append(registerDataMap, registerData)
+				c.queue.Add(nodeName)
 			}
+		})
+	}
+	return c
+}
+
+func (c *NodeResourceController) enqueueNode(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		fmt.Printf("[Error]Failed to get key of node: %v\n", err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// resyncNodes 重新协调全部节点，如 label-config 中的名称变化时
+func (c *NodeResourceController) resyncNodes() {
+	if !c.nodesSynced() {
+		return
+	}
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		fmt.Printf("[Error]Failed to list nodes of cluster %s: %v\n", c.cluster, err)
+		return
+	}
+	for _, node := range nodes {
+		c.enqueueNode(node)
+	}
+}
+
+// Run 启动 informer 及协调循环，ctx 取消后等待正在进行的协调结束再返回
+func (c *NodeResourceController) Run(ctx context.Context) error {
+	defer c.queue.ShutDown()
+
+	c.informerFactory.Start(ctx.Done())
+	defer c.informerFactory.Shutdown()
+	if c.registrations != nil {
+		c.registrations.factory.Start(ctx.Done())
+		defer c.registrations.factory.Shutdown()
+	}
+	// 等待 label-config 及 ComputeRegistration 同步，避免依赖其中静态字段的节点在首次协调时被注销
+	if !cache.WaitForCacheSync(ctx.Done(), c.nodesSynced, c.labelWatcher.HasSynced, c.registrations.HasSynced) {
+		if ctx.Err() != nil {
+			return nil
 		}
+		return fmt.Errorf("failed to sync node cache of cluster %s", c.cluster)
+	}
+	c.setStatus(func(status *ControllerStatus) { status.Synced = true })
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	// 同步周期内即使没有节点变化也上报一次，以便 Reporter 完成全量同步及失败后的重试
+	go func() {
+		defer wg.Done()
+		wait.UntilWithContext(ctx, func(ctx context.Context) { c.queue.Add(reportKey) }, c.options.SyncInterval)
+	}()
+	go func() {
+		defer wg.Done()
+		wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}()
+	<-ctx.Done()
+	c.queue.ShutDown()
+	wg.Wait()
+	return nil
+}
+
+func (c *NodeResourceController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *NodeResourceController) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	key := item.(string)
+	var err error
+	if key == reportKey {
+		c.report(ctx)
+	} else {
+		err = c.syncNode(ctx, key)
+	}
+	if err != nil {
+		fmt.Printf("[Error]Failed to sync node %s, requeued: %v\n", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(item)
+	return true
+}
 
-		//GPU 数据组装
-		chipNum, err = strconv.ParseInt(node.Labels[GPUChipNumber], 10, 64)
+// syncNode 重新组装节点的算力标识，有变化时安排一次上报；节点被删除后其算力标识不再上报，由 Reporter 注销。
+// 标签不合法或未要求注册的节点在此更新注册状态，其余节点在上报后更新
+func (c *NodeResourceController) syncNode(ctx context.Context, name string) error {
+	node, err := c.nodeLister.Get(name)
+	var resources nodeResources
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return err
+	default:
+		resources = assembleNodeResources(node, staticSources{
+			node:      c.registrations.forNode(name),
+			cluster:   c.registrations.forNode(""),
+			labelInfo: c.labelWatcher.GetLabelInfo(),
+		})
+	}
+
+	c.mutex.Lock()
+	old, ok := c.nodes[name]
+	if node == nil {
+		delete(c.nodes, name)
+	} else {
+		c.nodes[name] = resources
+	}
+	c.mutex.Unlock()
+
+	if ok != (node != nil) || !reflect.DeepEqual(old, resources) {
+		c.queue.AddAfter(reportKey, reportDelay)
+	}
+	if status, ok := validationStatus(resources); node != nil && ok {
+		c.updateNodeStatus(ctx, name, status)
+	}
+	return nil
+}
+
+// assembleNodeResources 按节点标签将节点的各类芯片（见 apis.ChipClasses）分组，未打 cncos.org/register=true 标签的节点需要注销；
+// 静态字段的其他来源见 staticField。标签不符合算力标识字段宽度时记录在 problems 中，该节点不组装任何芯片
+func assembleNodeResources(node *corev1.Node, sources staticSources) nodeResources {
+	var resources nodeResources
+	resources.register = node.Labels[Register] == "true"
+
+	codes := make([]string, len(staticFields))
+	for i, field := range staticFields {
+		code, err := field.code(node, sources)
 		if err != nil {
-			fmt.Println(chipNum, err)
+			resources.problems = append(resources.problems, err.Error())
+		}
+		codes[i] = code
+	}
+	// label 返回节点标签的值，超出字段宽度时记录问题
+	label := func(key string, width int) string {
+		value := node.Labels[key]
+		if len(value) > width {
+			resources.problems = append(resources.problems,
+				fmt.Sprintf("label %s: expected at most %d characters, got %q", key, width, value))
+		}
+		return value
+	}
+
+	// 创建 NodeResourceInfo 结构
+	info := apis.NodeResourceInfo{
+		City:         codes[0],
+		CompanyType:  codes[1],
+		Company:      codes[2],
+		ResourceType: codes[3],
+		ResourceAZ:   codes[4],
+		ServiceType:  codes[5],
+
+		// 动态采集，包含计算量、存储、网络带宽、功耗
+		StorageCapacity:   fmt.Sprintf("S%0*s", 7, label(StorageCapacity, 7)),
+		NetworkBandSwitch: fmt.Sprintf("N%0*s", 6, label(NetworkBandwidth, 6)),
+
+		// 新增需求， 网络、芯片的属性通过动态探测的方式完成，这部分解耦通过 daemon set 来上报处理
+		NetworkType:          fmt.Sprintf("%0*s", 2, label(NetworkType, 2)),
+		PowerResourceAddress: fmt.Sprintf("00" + fmt.Sprintf("%0*s", 32, label(NetworkAddress, 32))),
+	}
+
+	for _, class := range apis.ChipClasses {
+		chip := class.Labels()
+		// 没有芯片数量标签表示节点上没有该类芯片
+		if node.Labels[chip.Number] == "" {
+			continue
+		}
+		chipNum, err := strconv.ParseInt(node.Labels[chip.Number], 10, 64)
+		if err != nil || chipNum < 0 || chipNum > 99999 {
+			resources.problems = append(resources.problems,
+				fmt.Sprintf("label %s: expected a number of chips between 0 and 99999, got %q", chip.Number, node.Labels[chip.Number]))
+			continue
+		}
+		if chipNum == 0 {
 			continue
 		}
 
-		for i = 1; i <= chipNum; i++ {
-			info.ChipType = fmt.Sprintf("%0*s", 5, node.Labels[GPUChipType])
-			info.ChipModel = fmt.Sprintf("%0*s", 8, node.Labels[GPUChipModel])
-			info.ChipUniqNumber = fmt.Sprintf("%05d", i)
-			info.ComputeCapacity = fmt.Sprintf("F%0*s", 4, node.Labels[GPUComputeCapacity])
-			info.PowerConsumption = fmt.Sprintf("P%0*s", 5, node.Labels[GPUPowerConsumption])
+		// 未设置芯片类型标签时取该类芯片的编码
+		info.ChipType = class.ChipType
+		if node.Labels[chip.Type] != "" {
+			info.ChipType = fmt.Sprintf("%0*s", 5, label(chip.Type, 5))
+		}
+		info.ChipModel = fmt.Sprintf("%0*s", 8, label(chip.Model, 8))
+		info.ComputeCapacity = fmt.Sprintf("F%0*s", 4, label(chip.ComputeCapacity, 4))
+		info.PowerConsumption = fmt.Sprintf("P%0*s", 5, label(chip.PowerConsumption, 5))
+		resources.chips = append(resources.chips, chipGroup{info: info, count: chipNum})
+	}
 
-			// 城市>-行业>-企业>-资源类型>-数据中心>-服务类型>-计算、存储、网络及功耗>-网络类型>-算力互联网地址>-芯片类型>-芯片型号>-芯片唯一编号。
-			registerData := fmt.Sprintf("%s%s%s%s%s%s%s%s%s%s%s%s%s%s%s",
-				info.City, info.CompanyType, info.Company, info.ResourceType, info.ResourceAZ, info.ServiceType,
-				info.ComputeCapacity, info.StorageCapacity, info.NetworkBandSwitch, info.PowerConsumption,
-				info.NetworkType, info.PowerResourceAddress, info.ChipType, info.ChipModel, info.ChipUniqNumber)
+	if len(resources.problems) > 0 {
+		fmt.Printf("[Warning]Node %s is skipped: %s\n", node.Name, strings.Join(resources.problems, "; "))
+		resources.chips = nil
+	} else if !resources.register {
+		fmt.Printf("[Info]Node %s need not register and unregister it first. If you want to register it, "+
+			"label it with cncos.org/register=true\n", node.Name)
+	}
+	return resources
+}
 
-			if len(node.Labels[Register]) == 0 || node.Labels[Register] != "true" {
-				fmt.Printf("[Info]Node %s need not register and unregister it first. If you want to register it, "+
-					"label it with cncos.org/register=true\n", node.Name)
-				unRegisterDataMap = append(unRegisterDataMap, registerData)
-			} else {
-				// 将节点资源信息发送到 Web 服务器
-				registerDataMap = append(registerDataMap, registerData)
+// computeIDs 按上报模式将各节点的资源转换为需要注册及注销的算力标识，结果已排序
+func computeIDs(mode string, nodes map[string]nodeResources) (register, unregister []string) {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	// 按节点名排序，集群模式下合并后的算力互联网地址取第一个节点的地址
+	sort.Strings(names)
+
+	var registerNodes, unregisterNodes []nodeResources
+	for _, name := range names {
+		if nodes[name].register {
+			registerNodes = append(registerNodes, nodes[name])
+		} else {
+			unregisterNodes = append(unregisterNodes, nodes[name])
+		}
+	}
+	register, unregister = nodeIDs(mode, registerNodes), nodeIDs(mode, unregisterNodes)
+	sort.Strings(register)
+	sort.Strings(unregister)
+	return register, unregister
+}
+
+// nodeIDs 组装节点的算力标识：每颗芯片一个时芯片唯一编号为节点内序号；
+// 按节点或集群聚合时每个芯片组一个标识，芯片唯一编号为芯片数量，计算量、功耗仍为服务器总量（见 CPID/算力标识体系更新版.md 2.7）
+func nodeIDs(mode string, nodes []nodeResources) []string {
+	if mode == ReportPerCluster {
+		nodes = mergeNodes(nodes)
+	}
+
+	var ids []string
+	for _, resources := range nodes {
+		for _, chip := range resources.chips {
+			info := chip.info
+			if mode == ReportPerChip {
+				// 城市>-行业>-企业>-资源类型>-数据中心>-服务类型>-计算、存储、网络及功耗>-网络类型>-算力互联网地址>-芯片类型>-芯片型号>-芯片唯一编号。
+				for i := int64(1); i <= chip.count; i++ {
+					info.ChipUniqNumber = fmt.Sprintf("%05d", i)
+					ids = append(ids, apis.ResourceInfoToString(&info))
+				}
+				continue
 			}
+			count := chip.count
+			if count > 99999 {
+				fmt.Printf("[Warning]Aggregated chip count %d exceeds 5 digits, reported as 99999\n", count)
+				count = 99999
+			}
+			info.ChipUniqNumber = fmt.Sprintf("%05d", count)
+			ids = append(ids, apis.ResourceInfoToString(&info))
 		}
 	}
+	return ids
+}
 
-	// 将节点资源信息发送到 Web 服务器
-	fmt.Println(unRegisterDataMap)
-	fmt.Println(registerDataMap)
+// mergeNodes 合并静态字段、网络类型及各芯片类型、型号都相同的节点，各芯片组的芯片数量、计算量及功耗求和，即合并后服务器的总量；
+// 存储、网络带宽为数据中心的总量，不求和。合并后的算力互联网地址取第一个节点的地址，每个节点只属于一个合并结果，
+// 因此服务端按地址去重时每台服务器的计算量、功耗只计一次，与按芯片上报时的汇总一致
+func mergeNodes(nodes []nodeResources) []nodeResources {
+	index := map[string]int{}
+	var merged []nodeResources
+	for _, resources := range nodes {
+		if len(resources.chips) == 0 {
+			continue
+		}
+		key := nodeShapeKey(resources)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, nodeResources{register: resources.register, chips: append([]chipGroup(nil), resources.chips...)})
+			continue
+		}
 
-	unRegisterNodeResourcesToServer(unRegisterDataMap)
-	registerNodeResourcesToServer(registerDataMap)
+		// 芯片组按芯片类别的顺序排列，形状相同的节点逐个对应
+		for j, chip := range resources.chips {
+			m := &merged[i].chips[j]
+			m.count += chip.count
+			m.info.ComputeCapacity = sumCapacity(m.info.ComputeCapacity, chip.info.ComputeCapacity)
+			m.info.PowerConsumption = sumCapacity(m.info.PowerConsumption, chip.info.PowerConsumption)
+		}
+	}
+	return merged
 }
 
-func registerNodeResourcesToServer(registerData []string) {
-	// 在这里实现将节点资源信息发送到 Web 服务器的逻辑，
-	if os.Args[1] == "register" {
-		err := client.LoadConfig()
-		if err != nil {
-			fmt.Println("LoadConfig error")
-			return
+// nodeShapeKey 为集群模式下合并节点的依据：静态字段、网络类型，以及各芯片组的芯片类型及型号
+func nodeShapeKey(resources nodeResources) string {
+	var key strings.Builder
+	for i, chip := range resources.chips {
+		info := chip.info
+		if i == 0 {
+			key.WriteString(info.City + info.CompanyType + info.Company + info.ResourceType + info.ResourceAZ + info.ServiceType + info.NetworkType)
 		}
-		client.RegisterResource(registerData)
-	} else {
+		key.WriteString("/" + info.ChipType + info.ChipModel)
+	}
+	return key.String()
+}
+
+// sumCapacity 将两个同前缀、同宽度的容量字段（如 F0100）相加，超出字段宽度时取最大值；无法解析的值按 0 计算
+func sumCapacity(a, b string) string {
+	x, _ := strconv.ParseInt(a[1:], 10, 64)
+	y, _ := strconv.ParseInt(b[1:], 10, 64)
+	return formatCapacity(a[:1], len(a)-1, x+y)
+}
+
+func formatCapacity(prefix string, width int, value int64) string {
+	max := int64(1)
+	for i := 0; i < width; i++ {
+		max *= 10
+	}
+	if value >= max {
+		fmt.Printf("[Warning]Aggregated %s capacity %d exceeds %d digits, reported as %d\n", prefix, value, width, max-1)
+		value = max - 1
+	}
+	return fmt.Sprintf("%s%0*d", prefix, width, value)
+}
+
+// report 汇总全部节点的算力标识后上报
+func (c *NodeResourceController) report(ctx context.Context) {
+	c.mutex.Lock()
+	registerData, unRegisterData := computeIDs(c.options.ReportMode, c.nodes)
+	nodes := make(map[string]nodeResources, len(c.nodes))
+	for name, resources := range c.nodes {
+		nodes[name] = resources
+	}
+	c.mutex.Unlock()
+
+	if !c.options.Register {
+		fmt.Println(unRegisterData)
 		fmt.Println(registerData)
 	}
+	err := c.reportNodeResourcesToServer(ctx, registerData, unRegisterData)
+	// 标签合法且需要注册的节点，其注册状态取决于本次上报的结果
+	for name, resources := range nodes {
+		if _, ok := validationStatus(resources); !ok {
+			c.updateNodeStatus(ctx, name, reportStatus(resources, err))
+		}
+	}
+	c.updateRegistrations(ctx, nodes, registerData)
+}
+
+// Teardown 注销本控制器上报过的全部算力标识，在 Run 返回后调用，如集群被移除时
+func (c *NodeResourceController) Teardown(ctx context.Context) error {
+	c.mutex.Lock()
+	register, unregister := computeIDs(c.options.ReportMode, c.nodes)
+	c.nodes = map[string]nodeResources{}
+	c.mutex.Unlock()
+	ids := append(register, unregister...)
+	sort.Strings(ids)
+
+	if !c.options.Register {
+		return nil
+	}
+	if err := client.LoadConfig(); err != nil {
+		return err
+	}
+	// 集群移除后不再重放 outbox，注销须同步送达
+	result := c.reporter.Teardown(ctx, ids)
+	recordResult(result)
+	return result.Err()
+}
+
+// ControllerStatus 为单个集群控制器的运行状态
+type ControllerStatus struct {
+	// Synced 表示节点缓存已完成首次同步
+	Synced     bool      `json:"synced"`
+	LastReport time.Time `json:"lastReport,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+}
+
+func (c *NodeResourceController) Status() ControllerStatus {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return c.status
 }
 
-func unRegisterNodeResourcesToServer(unRegisterDataMap []string) {
+func (c *NodeResourceController) setStatus(update func(status *ControllerStatus)) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	update(&c.status)
+}
+
+// reportNodeResourcesToServer 只向各 server 上报与上次确认状态相比的变化，周期性或 server 上缺少已确认的标识时全量同步，
+// 返回注册的错误及不支持注销的 server 上残留标识的错误
+func (c *NodeResourceController) reportNodeResourcesToServer(ctx context.Context, registerData, unRegisterData []string) error {
+	if !c.options.Register {
+		return nil
+	}
+
 	err := client.LoadConfig()
 	if err != nil {
 		fmt.Println("server can't connect because of config is invalid")
-	} else {
-		for _, registerData := range unRegisterDataMap {
-			nodeInfo := apis.ParseResourceInfo(registerData)
-			client.UnregisterResource(nodeInfo.ID)
+		return fmt.Errorf("invalid server config: %v", err)
+	}
+
+	report := c.reporter.Report(ctx, registerData, unRegisterData)
+	recordReport(report)
+	recordOutboxDepth(c.cluster, c.reporter.OutboxDepth())
+	for server, reason := range report.FullResync {
+		fmt.Printf("[Info]Full resync to %s: %s\n", server, reason)
+	}
+	var errs []string
+	for _, result := range []client.RegistrationResult{report.Register, report.Unregister} {
+		if err := result.Err(); err != nil {
+			fmt.Printf("[Error]%v\n", err)
+			errs = append(errs, err.Error())
 		}
 	}
+	c.setStatus(func(status *ControllerStatus) {
+		status.LastReport = time.Now()
+		status.LastError = strings.Join(errs, "; ")
+	})
+	// 不支持注销的 server 上残留的标识需要人工处理，与注册错误一起计入节点状态；其他注销失败会自动重试
+	var failed []string
+	if err := report.Register.Err(); err != nil {
+		failed = append(failed, err.Error())
+	}
+	for _, server := range report.Unregister.Failed() {
+		if errors.Is(server.Err, client.ErrNotSupported) {
+			failed = append(failed, server.Err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
 }
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"register-power-resources/pkg/apis"
+	"register-power-resources/pkg/client"
+	"register-power-resources/pkg/server"
+)
+
+func TestAssembleComputeIDsResolvesNames(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				Register:         "true",
+				Company:          "20001",
+				ResourceTypeName: "ai",
+				ResourceAZName:   "az2",
+				CPUChipNumber:    "1",
+				CPUChipType:      "00001",
+				CPUChipModel:     "1",
+			},
+			Annotations: map[string]string{
+				CityName:        "北京市",
+				ServiceTypeName: "容器服务,GPU 云服务器",
+			},
+		},
+	}
+	labelInfo := apis.LabelInfo{IndustryName: "计算机", EnterpriseName: "其他"}
+
+	resources := assembleNodeResources(node, staticSources{labelInfo: labelInfo})
+	if len(resources.chips) != 1 {
+		t.Fatalf("expected 1 chip group, got %v", resources.chips)
+	}
+	// 编码标签优先于 label-config 中的企业名称
+	info := resources.chips[0].info
+	if static := info.City + info.CompanyType + info.Company + info.ResourceType + info.ResourceAZ + info.ServiceType; static != "1101cp20001402502"+"02602001601004" {
+		t.Fatalf("unexpected static fields %q", static)
+	}
+
+	// 服务类型字段固定为 2 个服务
+	node.Annotations[ServiceTypeName] = "容器服务"
+	resources = assembleNodeResources(node, staticSources{labelInfo: labelInfo})
+	if len(resources.chips) != 0 || len(resources.problems) != 1 ||
+		!strings.Contains(resources.problems[0], "exactly 2 service types are required") {
+		t.Fatalf("unexpected result for a single service type: %v", resources.problems)
+	}
+	node.Annotations[ServiceTypeName] = "容器服务,GPU 云服务器"
+
+	node.Annotations[CityName] = "不存在的城市"
+	if resources := assembleNodeResources(node, staticSources{labelInfo: labelInfo}); len(resources.chips) != 0 {
+		t.Fatalf("expected no chips for an unknown city, got %v", resources.chips)
+	}
+}
+
+func TestComputeIDsReportModes(t *testing.T) {
+	gpuNode := func(name, address, gpus, capacity string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+			Register: "true", City: "1101", CompanyType: "cp", Company: "20001", ResourceType: "402",
+			ResourceAZ: "501", ServiceType: "02602001601004", NetworkAddress: address,
+			StorageCapacity: "100", NetworkBandwidth: "10",
+			CPUChipNumber: "0", GPUChipNumber: gpus, GPUChipType: "00000", GPUChipModel: "1010",
+			GPUComputeCapacity: capacity, GPUPowerConsumption: "300",
+		}}}
+	}
+	nodes := map[string]nodeResources{
+		"node-1": assembleNodeResources(gpuNode("node-1", "1", "8", "600"), staticSources{}),
+		"node-2": assembleNodeResources(gpuNode("node-2", "10", "4", "300"), staticSources{}),
+	}
+
+	for mode, expected := range map[string]int{ReportPerChip: 12, ReportPerNode: 2, ReportPerCluster: 1} {
+		if register, _ := computeIDs(mode, nodes); len(register) != expected {
+			t.Errorf("%s: expected %d compute ids, got %d", mode, expected, len(register))
+		}
+	}
+
+	// 芯片唯一编号为芯片数量，计算量、功耗为合并的服务器总量之和，存储、网络带宽为数据中心总量不求和
+	register, _ := computeIDs(ReportPerCluster, nodes)
+	info := apis.ParseResourceInfo(register[0])
+	if info.ComputeCapacity != "F0900" || info.StorageCapacity != "S0000100" || info.PowerConsumption != "P00600" ||
+		info.ChipUniqNumber != "00012" || info.IPv4() != "0.0.0.1" {
+		t.Fatalf("unexpected aggregated id %+v", info)
+	}
+
+	// 按节点聚合时计算量、功耗仍为服务器总量，不乘以芯片数量
+	node := gpuNode("node-3", "11", "2", "100")
+	node.Labels[CPUChipNumber], node.Labels[CPUChipType], node.Labels[CPUComputeCapacity] = "2", "00001", "100"
+	register, _ = computeIDs(ReportPerNode, map[string]nodeResources{"node-3": assembleNodeResources(node, staticSources{})})
+	for _, id := range register {
+		if info := apis.ParseResourceInfo(id); info.ComputeCapacity != "F0100" || info.ChipUniqNumber != "00002" {
+			t.Fatalf("unexpected per-node id %+v", info)
+		}
+	}
+	if len(register) != 2 {
+		t.Fatalf("expected one id per chip model, got %v", register)
+	}
+}
+
+func TestReportModesStatsTotals(t *testing.T) {
+	node := func(address, gpus, capacity, power string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+			Register: "true", City: "1101", CompanyType: "cp", Company: "20001", ResourceType: "402",
+			ResourceAZ: "501", ServiceType: "02602001601004", NetworkAddress: address,
+			StorageCapacity: "100", NetworkBandwidth: "10",
+			GPUChipNumber: gpus, GPUChipType: "00000", GPUChipModel: "1010",
+			GPUComputeCapacity: capacity, GPUPowerConsumption: power,
+		}}}
+	}
+	// node-1 同时有 CPU、GPU，两类芯片的计算量、功耗标签均为服务器总量；node-2 至 node-4 在集群模式下合并
+	mixed := node("1", "8", "600", "3000")
+	for key, value := range map[string]string{CPUChipNumber: "2", CPUChipType: "00001", CPUChipModel: "2020",
+		CPUComputeCapacity: "600", CPUPowerConsumption: "3000"} {
+		mixed.Labels[key] = value
+	}
+	nodes := map[string]nodeResources{
+		"node-1": assembleNodeResources(mixed, staticSources{}),
+		"node-2": assembleNodeResources(node("10", "4", "300", "1500"), staticSources{}),
+		"node-3": assembleNodeResources(node("11", "4", "300", "1500"), staticSources{}),
+		"node-4": assembleNodeResources(node("100", "2", "200", "800"), staticSources{}),
+	}
+
+	post := func(handler http.HandlerFunc, path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		return w
+	}
+	expected := apis.RegistryStats{ComputePFLOPs: 1400, StorageGB: 100, BandwidthMbps: 10, PowerWatts: 6800}
+	for _, mode := range []string{ReportPerChip, ReportPerNode, ReportPerCluster} {
+		register, _ := computeIDs(mode, nodes)
+		if w := post(server.RegisterResource, "/resources", client.RequestBody{ComputeIDs: register}); w.Code != http.StatusCreated {
+			t.Fatalf("%s: register: %d %s", mode, w.Code, w.Body)
+		}
+
+		w := httptest.NewRecorder()
+		server.Stats(w, httptest.NewRequest(http.MethodGet, "/v1/stats", nil))
+		var stats apis.RegistryStats
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+			t.Fatal(err)
+		}
+		if stats.ComputePFLOPs != expected.ComputePFLOPs || stats.StorageGB != expected.StorageGB ||
+			stats.BandwidthMbps != expected.BandwidthMbps || stats.PowerWatts != expected.PowerWatts {
+			t.Errorf("%s: unexpected totals %+v", mode, stats)
+		}
+
+		if w := post(server.UnregisterResources, "/v1/resources/unregister", client.RequestBody{ComputeIDs: register}); w.Code != http.StatusOK {
+			t.Fatalf("%s: unregister: %d %s", mode, w.Code, w.Body)
+		}
+	}
+}
+
+func TestAssembleAcceleratorClasses(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+		Register: "true", City: "1101", CompanyType: "cp", Company: "20001", ResourceType: "402",
+		ResourceAZ: "501", ServiceType: "02602001601004",
+		"cncos.org/npu-chip-number": "8", "cncos.org/npu-chip-model": "00000100",
+		"cncos.org/dpu-chip-number":  "2",
+		"cncos.org/fpga-chip-number": "1", "cncos.org/fpga-chip-type": "00011",
+	}}}
+
+	// 未设置芯片类型标签时取该类芯片的编码，设置时以标签为准
+	chipTypes := map[string]int64{}
+	for _, chip := range assembleNodeResources(node, staticSources{}).chips {
+		chipTypes[chip.info.ChipType] += chip.count
+	}
+	expected := map[string]int64{apis.ChipTypeNPU: 8, apis.ChipTypeOther: 2, apis.ChipTypeASIC: 1}
+	if len(chipTypes) != len(expected) {
+		t.Fatalf("unexpected chip types %v", chipTypes)
+	}
+	for chipType, count := range expected {
+		if chipTypes[chipType] != count {
+			t.Fatalf("unexpected chip types %v", chipTypes)
+		}
+	}
+}
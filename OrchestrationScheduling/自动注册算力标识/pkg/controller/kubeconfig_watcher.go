@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// KubeconfigLabel 为保存成员集群 kubeconfig 的 ConfigMap 及 Secret 的标签
+const KubeconfigLabel = "cluster_resources_register_kubeconfig"
+
+// KubeconfigNamespace 为保存成员集群 kubeconfig 的命名空间
+const KubeconfigNamespace = "kube-system"
+
+// kubeconfig 来源中的数据键
+const (
+	// KubeconfigKey 为完整的 kubeconfig
+	KubeconfigKey = "kubeconfig"
+	// KubeconfigSecretKey 为 ConfigMap 引用的同命名空间 Secret 名称，kubeconfig 从该 Secret 中读取
+	KubeconfigSecretKey = "kubeconfigSecret"
+	// 成员集群 ServiceAccount 的 token 及 CA，与 server 一起组装为 kubeconfig
+	ServerKey = "server"
+	TokenKey  = corev1.ServiceAccountTokenKey
+	CAKey     = corev1.ServiceAccountRootCAKey
+)
+
+// kubeconfigSecretResync 为 ConfigMap 的 resync 周期，被引用的 Secret 不做 watch，按此周期重新读取以发现轮换
+const kubeconfigSecretResync = time.Minute
+
+// secretGetTimeout 为读取被引用 Secret 的最长时间
+const secretGetTimeout = 10 * time.Second
+
+// KubeconfigWatcher 监听带有 cluster_resources_register_kubeconfig=true 标签的 ConfigMap，以及开启
+// -kubeconfig-secrets 时带同一标签的 Secret，以名称为集群名交给 ClusterManager 启动、重启或移除对应的控制器。
+// 同名时 Secret 优先；ConfigMap 也可以通过 kubeconfigSecret 引用 Secret，该 Secret 按名称直接读取而不缓存，
+// 每个 resync 周期重新读取，轮换后控制器按新凭据重启
+type KubeconfigWatcher struct {
+	clientset kubernetes.Interface
+	manager   *ClusterManager
+
+	configMapFactory informers.SharedInformerFactory
+	secretFactory    informers.SharedInformerFactory
+	configMaps       corelisters.ConfigMapLister
+	secrets          corelisters.SecretLister
+	synced           []cache.InformerSynced
+	queue            workqueue.RateLimitingInterface
+}
+
+func NewKubeconfigWatcher(clientset kubernetes.Interface, manager *ClusterManager) *KubeconfigWatcher {
+	labelled := informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = labels.Set{KubeconfigLabel: "true"}.AsSelector().String()
+	})
+	configMapFactory := informers.NewSharedInformerFactoryWithOptions(clientset, kubeconfigSecretResync,
+		informers.WithNamespace(KubeconfigNamespace), labelled)
+	configMapInformer := configMapFactory.Core().V1().ConfigMaps()
+
+	w := &KubeconfigWatcher{
+		clientset:        clientset,
+		manager:          manager,
+		configMapFactory: configMapFactory,
+		configMaps:       configMapInformer.Lister(),
+		synced:           []cache.InformerSynced{configMapInformer.Informer().HasSynced},
+		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "kubeconfigs"),
+	}
+	configMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) { w.enqueue(newObj) },
+		DeleteFunc: w.enqueue,
+	})
+	if !manager.options.KubeconfigSecrets {
+		return w
+	}
+
+	// 只缓存带标签的 Secret；RBAC 无法按标签授权，开启时需要 kube-system 中 Secret 的 list、watch 权限
+	w.secretFactory = informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithNamespace(KubeconfigNamespace), labelled)
+	secretInformer := w.secretFactory.Core().V1().Secrets()
+	w.secrets = secretInformer.Lister()
+	w.synced = append(w.synced, secretInformer.Informer().HasSynced)
+	// 标签被移除时 informer 产生删除事件，集群回退到同名的 ConfigMap 或被移除
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) { w.enqueue(newObj) },
+		DeleteFunc: w.enqueue,
+	})
+	return w
+}
+
+// enqueue 以对象名称（即集群名）入队
+func (w *KubeconfigWatcher) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		fmt.Printf("[Error]Failed to get key of kubeconfig source: %v\n", err)
+		return
+	}
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		fmt.Printf("[Error]Invalid key %q: %v\n", key, err)
+		return
+	}
+	w.queue.Add(name)
+}
+
+// Run 按事件依次启动、重启或移除集群控制器，ctx 取消后返回
+func (w *KubeconfigWatcher) Run(ctx context.Context) {
+	defer w.queue.ShutDown()
+
+	w.configMapFactory.Start(ctx.Done())
+	if w.secretFactory != nil {
+		w.secretFactory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), w.synced...) {
+		return
+	}
+
+	// 单个 worker 保证同一集群的启动、重启与移除按事件顺序执行
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for w.processNextItem() {
+		}
+	}, time.Second)
+	<-ctx.Done()
+}
+
+func (w *KubeconfigWatcher) processNextItem() bool {
+	item, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(item)
+
+	if err := w.sync(item.(string)); err != nil {
+		fmt.Printf("[Error]Failed to sync kubeconfig of cluster %s, requeued: %v\n", item, err)
+		w.queue.AddRateLimited(item)
+		return true
+	}
+	w.queue.Forget(item)
+	return true
+}
+
+func (w *KubeconfigWatcher) sync(name string) error {
+	kubeconfig, found, err := w.kubeconfig(name)
+	if err != nil {
+		return err
+	}
+	if !found {
+		// 来源删除后停止控制器并注销该集群的算力标识
+		w.manager.Remove(name)
+		return nil
+	}
+	if len(kubeconfig) == 0 {
+		fmt.Printf("Warning: kubeconfig source %s does not contain kubeconfig data\n", name)
+		return nil
+	}
+	w.manager.Apply(name, kubeconfig)
+	return nil
+}
+
+// kubeconfig 返回集群 name 的 kubeconfig，found 为 false 表示没有对应的来源
+func (w *KubeconfigWatcher) kubeconfig(name string) (kubeconfig []byte, found bool, err error) {
+	if w.secrets != nil {
+		secret, err := w.secrets.Secrets(KubeconfigNamespace).Get(name)
+		if err == nil {
+			kubeconfig, err = kubeconfigFromSecret(secret)
+			return kubeconfig, true, err
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, false, err
+		}
+	}
+
+	cm, err := w.configMaps.ConfigMaps(KubeconfigNamespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	secretName := cm.Data[KubeconfigSecretKey]
+	if secretName == "" {
+		return []byte(cm.Data[KubeconfigKey]), true, nil
+	}
+	// 按名称读取，RBAC 可用 resourceNames 只授权被引用的 Secret
+	ctx, cancel := context.WithTimeout(context.Background(), secretGetTimeout)
+	defer cancel()
+	secret, err := w.clientset.CoreV1().Secrets(KubeconfigNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		// Secret 尚未创建时保留原控制器，之后重试
+		return nil, true, fmt.Errorf("kubeconfig secret %s referenced by ConfigMap %s: %v", secretName, name, err)
+	}
+	kubeconfig, err = kubeconfigFromSecret(secret)
+	return kubeconfig, true, err
+}
+
+// kubeconfigFromSecret 读取 Secret 中的 kubeconfig，或用成员集群 ServiceAccount 的 token、ca.crt 及 server 组装 kubeconfig
+func kubeconfigFromSecret(secret *corev1.Secret) ([]byte, error) {
+	if kubeconfig := secret.Data[KubeconfigKey]; len(kubeconfig) > 0 {
+		return kubeconfig, nil
+	}
+
+	server, token := string(secret.Data[ServerKey]), string(secret.Data[TokenKey])
+	if server == "" && token == "" {
+		return nil, nil
+	}
+	if server == "" || token == "" {
+		return nil, fmt.Errorf("secret %s must contain both %s and %s", secret.Name, ServerKey, TokenKey)
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[secret.Name] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: secret.Data[CAKey]}
+	config.AuthInfos[secret.Name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[secret.Name] = &clientcmdapi.Context{Cluster: secret.Name, AuthInfo: secret.Name}
+	config.CurrentContext = secret.Name
+	return clientcmd.Write(*config)
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestKubeconfigFromServiceAccountTokenSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "member"},
+		Data: map[string][]byte{
+			ServerKey: []byte("https://member.example:6443"),
+			TokenKey:  []byte("sa-token"),
+		},
+	}
+	kubeconfig, err := kubeconfigFromSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://member.example:6443" || config.BearerToken != "sa-token" {
+		t.Fatalf("unexpected rest config host %q token %q", config.Host, config.BearerToken)
+	}
+
+	delete(secret.Data, TokenKey)
+	if _, err := kubeconfigFromSecret(secret); err == nil {
+		t.Fatal("expected an error for a secret without token")
+	}
+}
+
+func TestReferencedSecretReadByName(t *testing.T) {
+	var requests []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.URL.Path != "/api/v1/namespaces/kube-system/secrets/member-credentials" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "member-credentials", Namespace: KubeconfigNamespace},
+			Data:       map[string][]byte{ServerKey: []byte("https://member.example:6443"), TokenKey: []byte("sa-token")},
+		})
+	}))
+	defer apiServer.Close()
+	clientset := kubernetes.NewForConfigOrDie(&rest.Config{Host: apiServer.URL})
+
+	// 默认不监听 Secret
+	w := NewKubeconfigWatcher(clientset, NewClusterManager(NewOptions(), nil))
+	if w.secretFactory != nil || len(w.synced) != 1 {
+		t.Fatal("secrets watched without -kubeconfig-secrets")
+	}
+	w.configMapFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "member", Namespace: KubeconfigNamespace},
+		Data:       map[string]string{KubeconfigSecretKey: "member-credentials"},
+	})
+
+	kubeconfig, found, err := w.kubeconfig("member")
+	if err != nil || !found {
+		t.Fatalf("kubeconfig found %v: %v", found, err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "https://member.example:6443" {
+		t.Fatalf("unexpected host %q", config.Host)
+	}
+	if len(requests) != 1 || requests[0] != "GET /api/v1/namespaces/kube-system/secrets/member-credentials" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	if _, found, _ := w.kubeconfig("absent"); found {
+		t.Fatal("cluster without source reported as found")
+	}
+}
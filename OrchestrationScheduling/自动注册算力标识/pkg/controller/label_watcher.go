@@ -2,69 +2,143 @@ package controller
 
 import (
 	"context"
-	corev1 "k8s.io/api/core/v1"
+	"fmt"
+	"sort"
 	"sync"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"register-power-resources/pkg/apis"
 )
 
+// LabelConfigLabel 为保存静态字段名称的 ConfigMap 的标签
+const LabelConfigLabel = "label-config"
+
+// LabelWatcher 监听带有 label-config=true 标签的 ConfigMap，其中的名称在节点未设置对应标签时用于组装算力标识
 type LabelWatcher struct {
-	clientset  *kubernetes.Clientset
-	labelInfo  apis.LabelInfo
+	factory    informers.SharedInformerFactory
+	configMaps corelisters.ConfigMapLister
+	synced     cache.InformerSynced
+
 	labelMutex sync.RWMutex
+	labelInfo  apis.LabelInfo
+	handlers   []func()
+	// ready 在缓存同步后首次读取名称并执行回调后置为 true
+	ready bool
 }
 
-func NewLabelWatcher(clientset *kubernetes.Clientset) *LabelWatcher {
-	return &LabelWatcher{clientset: clientset}
-}
+func NewLabelWatcher(clientset kubernetes.Interface) *LabelWatcher {
+	selector := labels.Set{LabelConfigLabel: "true"}.AsSelector().String()
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) { options.LabelSelector = selector }))
+	informer := factory.Core().V1().ConfigMaps()
 
-func (w *LabelWatcher) Run() {
-	labelSelector := labels.Set{"label-config": "true"}.AsSelector()
-	options := metav1.ListOptions{LabelSelector: labelSelector.String()}
+	w := &LabelWatcher{factory: factory, configMaps: informer.Lister(), synced: informer.Informer().HasSynced}
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { w.update() },
+		UpdateFunc: func(interface{}, interface{}) { w.update() },
+		DeleteFunc: func(interface{}) { w.update() },
+	})
+	return w
+}
 
-	// 监听带有 "label-config=true" 标签的 ConfigMap
-	watcher, err := w.clientset.CoreV1().ConfigMaps("").Watch(context.Background(), options)
-	if err != nil {
-		panic(err.Error())
+// Run 启动 informer，缓存同步后读取一次名称，ctx 取消后返回
+func (w *LabelWatcher) Run(ctx context.Context) {
+	w.factory.Start(ctx.Done())
+	if cache.WaitForCacheSync(ctx.Done(), w.synced) {
+		// 没有 label-config ConfigMap 时不会收到事件，同步后主动读取一次
+		w.update()
+		w.labelMutex.Lock()
+		w.ready = true
+		w.labelMutex.Unlock()
 	}
+	<-ctx.Done()
+	w.factory.Shutdown()
+}
 
-	// 处理 ConfigMap 事件
-	for event := range watcher.ResultChan() {
-		cm, ok := event.Object.(*corev1.ConfigMap)
-		if !ok {
-			continue
-		}
-
-		switch event.Type {
-		case watch.Added, watch.Modified:
-			// 当 ConfigMap 添加或修改时，更新标签信息
-			w.updateLabelInfo(cm)
-		case watch.Deleted:
-			// 当 ConfigMap 删除时，清除标签信息
-			w.labelMutex.Lock()
-			w.labelInfo = apis.LabelInfo{}
-			w.labelMutex.Unlock()
-		}
+// HasSynced 表示 ConfigMap 缓存已完成首次同步，且名称已读取、OnChange 回调已执行；未启动的 LabelWatcher（nil）视为已同步
+func (w *LabelWatcher) HasSynced() bool {
+	if w == nil {
+		return true
 	}
+	w.labelMutex.RLock()
+	defer w.labelMutex.RUnlock()
+	return w.ready
+}
+
+// OnChange 注册名称变化时的回调，如重新协调全部节点
+func (w *LabelWatcher) OnChange(handler func()) {
+	w.labelMutex.Lock()
+	defer w.labelMutex.Unlock()
+	w.handlers = append(w.handlers, handler)
 }
 
 func (w *LabelWatcher) GetLabelInfo() apis.LabelInfo {
+	if w == nil {
+		return apis.LabelInfo{}
+	}
 	w.labelMutex.RLock()
 	defer w.labelMutex.RUnlock()
 	return w.labelInfo
 }
 
-func (w *LabelWatcher) updateLabelInfo(cm *corev1.ConfigMap) {
+// update 重新读取名称，存在多个 label-config ConfigMap 时按命名空间及名称取第一个
+func (w *LabelWatcher) update() {
+	configMaps, err := w.configMaps.List(labels.Everything())
+	if err != nil {
+		fmt.Printf("[Error]Failed to list label-config ConfigMaps: %v\n", err)
+		return
+	}
+	sort.Slice(configMaps, func(i, j int) bool {
+		if configMaps[i].Namespace != configMaps[j].Namespace {
+			return configMaps[i].Namespace < configMaps[j].Namespace
+		}
+		return configMaps[i].Name < configMaps[j].Name
+	})
+
+	var info apis.LabelInfo
+	if len(configMaps) > 0 {
+		cm := configMaps[0]
+		if len(configMaps) > 1 {
+			fmt.Printf("[Warning]Found %d label-config ConfigMaps, using %s/%s\n", len(configMaps), cm.Namespace, cm.Name)
+		}
+		info = labelInfoFromConfigMap(cm)
+	}
+
 	w.labelMutex.Lock()
-	defer w.labelMutex.Unlock()
+	changed := info != w.labelInfo
+	w.labelInfo = info
+	handlers := w.handlers
+	w.labelMutex.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler()
+		}
+	}
+}
 
-	w.labelInfo.CityName = cm.Data["cityName"]
-	w.labelInfo.IndustryName = cm.Data["industryName"]
-	w.labelInfo.EnterpriseName = cm.Data["enterpriseName"]
-	w.labelInfo.ResourceType = cm.Data["resourceType"]
-	w.labelInfo.ServiceType = cm.Data["serviceType"]
+// labelInfoFromConfigMap 读取 ConfigMap 中的名称，无法识别的名称只告警，使用该字段的节点在协调时跳过
+func labelInfoFromConfigMap(cm *corev1.ConfigMap) apis.LabelInfo {
+	info := apis.LabelInfo{
+		CityName:       cm.Data["cityName"],
+		IndustryName:   cm.Data["industryName"],
+		EnterpriseName: cm.Data["enterpriseName"],
+		ResourceType:   cm.Data["resourceType"],
+		DataCenterName: cm.Data["dataCenterName"],
+		ServiceType:    cm.Data["serviceType"],
+	}
+	for _, field := range staticFields {
+		if name := field.configName(info); name != "" {
+			if _, err := field.resolve(name); err != nil {
+				fmt.Printf("[Warning]label-config %s/%s: %s: %v\n", cm.Namespace, cm.Name, field.configKey, err)
+			}
+		}
+	}
+	return info
 }
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestLabelWatcherSyncedAfterHandlers(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/configmaps" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"kind":"ConfigMapList","apiVersion":"v1","metadata":{"resourceVersion":"1"},"items":[
+{"metadata":{"name":"label-config","namespace":"cncos-system","labels":{"label-config":"true"}},"data":{"cityName":"北京"}}]}`)
+	}))
+	defer apiServer.Close()
+
+	w := NewLabelWatcher(kubernetes.NewForConfigOrDie(&rest.Config{Host: apiServer.URL}))
+	var handled int32
+	w.OnChange(func() {
+		// 回调执行期间尚未同步
+		if w.HasSynced() {
+			t.Error("synced before the handler finished")
+		}
+		atomic.AddInt32(&handled, 1)
+	})
+	if w.HasSynced() {
+		t.Fatal("synced before start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !w.HasSynced() {
+		if time.Now().After(deadline) {
+			t.Fatal("label watcher not synced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&handled) == 0 || w.GetLabelInfo().CityName != "北京" {
+		t.Fatalf("synced before names were read: handled %d, %+v", atomic.LoadInt32(&handled), w.GetLabelInfo())
+	}
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderStatus 为 /healthz/leader 返回的选主状态
+type LeaderStatus struct {
+	// Enabled 为 false 时未启用选主，本实例始终运行集群控制器
+	Enabled  bool   `json:"enabled"`
+	Identity string `json:"identity,omitempty"`
+	Leader   bool   `json:"leader"`
+	// Holder 为当前持有 Lease 的实例
+	Holder string     `json:"holder,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+// LeaderElection 通过 Lease 选出唯一运行集群控制器的实例，避免多副本或滚动更新期间重复注册、注销
+type LeaderElection struct {
+	identity string
+	elector  *leaderelection.LeaderElector
+
+	mutex  sync.Mutex
+	holder string
+	since  *time.Time
+}
+
+// NewLeaderElection 创建选主，run 在成为 leader 后执行，ctx 在失去领导权时取消
+func NewLeaderElection(clientset kubernetes.Interface, options *Options, run func(ctx context.Context)) (*LeaderElection, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname as leader election identity: %v", err)
+	}
+	l := &LeaderElection{identity: identity}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: options.LeaderElectionNamespace, Name: options.LeaderElectionName},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	l.elector, err = leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   options.LeaseDuration,
+		RenewDeadline:   options.RenewDeadline,
+		RetryPeriod:     options.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            options.LeaderElectionName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				fmt.Printf("[Info]%s became the leader, starting cluster controllers\n", identity)
+				isLeader.Set(1)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				fmt.Printf("[Info]%s stopped leading\n", identity)
+				isLeader.Set(0)
+			},
+			OnNewLeader: l.observe,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader election config: %v", err)
+	}
+	return l, nil
+}
+
+// Run 参与选主直到 ctx 取消或失去领导权；ctx 取消时释放 Lease，新的 leader 无需等待 Lease 过期
+func (l *LeaderElection) Run(ctx context.Context) {
+	l.elector.Run(ctx)
+}
+
+func (l *LeaderElection) observe(holder string) {
+	now := time.Now()
+	l.mutex.Lock()
+	l.holder, l.since = holder, &now
+	l.mutex.Unlock()
+	if holder != l.identity {
+		fmt.Printf("[Info]%s is the leader, waiting\n", holder)
+	}
+}
+
+// Status 返回选主状态，l 为 nil 表示未启用选主
+func (l *LeaderElection) Status() LeaderStatus {
+	if l == nil {
+		return LeaderStatus{Leader: true}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return LeaderStatus{
+		Enabled:  true,
+		Identity: l.identity,
+		Leader:   l.elector.IsLeader(),
+		Holder:   l.holder,
+		Since:    l.since,
+	}
+}
+
+// ServeHTTP 提供 /healthz/leader。非 leader 的实例同样健康，始终返回 200
+func (l *LeaderElection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.Status())
+}
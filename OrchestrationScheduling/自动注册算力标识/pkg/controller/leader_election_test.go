@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeLeaseServer 在内存中保存一个 Lease，只提供 get、create 及 update
+type fakeLeaseServer struct {
+	mutex sync.Mutex
+	lease []byte
+}
+
+func (s *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		if s.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+	case http.MethodPost, http.MethodPut:
+		s.lease, _ = io.ReadAll(r.Body)
+	}
+	w.Write(s.lease)
+}
+
+func (s *fakeLeaseServer) holder() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var lease coordinationv1.Lease
+	json.Unmarshal(s.lease, &lease)
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func TestLeaderElection(t *testing.T) {
+	leases := &fakeLeaseServer{}
+	apiServer := httptest.NewServer(leases)
+	defer apiServer.Close()
+	clientset := kubernetes.NewForConfigOrDie(&rest.Config{Host: apiServer.URL})
+
+	options := NewOptions()
+	options.LeaseDuration, options.RenewDeadline, options.RetryPeriod = 2*time.Second, time.Second, 100*time.Millisecond
+	if err := options.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	leader, err := NewLeaderElection(clientset, options, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := leader.Status(); status.Leader {
+		t.Fatalf("leader before election: %+v", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		leader.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not elected as leader")
+	}
+
+	recorder := httptest.NewRecorder()
+	leader.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz/leader", nil))
+	var status LeaderStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if !status.Enabled || !status.Leader || status.Identity == "" || leases.holder() != status.Identity {
+		t.Fatalf("unexpected leader status %+v, lease held by %q", status, leases.holder())
+	}
+
+	// 退出时释放 Lease
+	cancel()
+	<-done
+	if holder := leases.holder(); holder != "" {
+		t.Fatalf("lease still held by %q after cancellation", holder)
+	}
+
+	// 未启用选主时始终为 leader
+	if status := (*LeaderElection)(nil).Status(); status.Enabled || !status.Leader {
+		t.Fatalf("unexpected status without leader election %+v", status)
+	}
+}
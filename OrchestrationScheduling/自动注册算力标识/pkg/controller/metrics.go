@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"register-power-resources/pkg/client"
+	"register-power-resources/pkg/metrics"
+)
+
+var (
+	metricsRegistry = metrics.NewRegistry()
+
+	serverRequests = metrics.NewCounterVec("cncos_controller_server_requests_total",
+		"Register and unregister operations sent to each server, by outcome.", "server", "operation", "outcome")
+	serverAttempts = metrics.NewCounterVec("cncos_controller_server_attempts_total",
+		"HTTP attempts made to each server, including retries.", "server", "operation")
+	acceptedIDs = metrics.NewCounterVec("cncos_controller_accepted_ids_total",
+		"Compute ids accepted by each server.", "server", "operation")
+	circuitOpen = metrics.NewGaugeVec("cncos_controller_circuit_open",
+		"Whether the circuit breaker of a server is open (1) or closed (0).", "server")
+	fullResyncs = metrics.NewCounterVec("cncos_controller_full_resyncs_total",
+		"Full re-registrations to each server, by reason.", "server", "reason")
+	lastSuccess = metrics.NewGaugeVec("cncos_controller_last_success_timestamp_seconds",
+		"Unix time of the last successful operation on each server.", "server", "operation")
+	outboxDepth = metrics.NewGaugeVec("cncos_controller_outbox_depth",
+		"Operations waiting in the outbox of each cluster for each server.", "cluster", "server")
+	isLeader = metrics.NewGaugeVec("cncos_controller_leader",
+		"Whether this instance holds the leader election lease (1) or not (0).")
+)
+
+func init() {
+	metricsRegistry.MustRegister(serverRequests, serverAttempts, acceptedIDs, circuitOpen, fullResyncs, lastSuccess,
+		outboxDepth, isLeader)
+}
+
+// recordReport 将一次增量上报的结果计入指标
+func recordReport(report client.ReportResult) {
+	recordResult(report.Register)
+	recordResult(report.Unregister)
+	for server, reason := range report.FullResync {
+		fullResyncs.Inc(server, reason)
+	}
+}
+
+// recordOutboxDepth 记录集群 cluster 在各 server 的 outbox 积压
+func recordOutboxDepth(cluster string, depth map[string]int) {
+	for server, n := range depth {
+		outboxDepth.Set(float64(n), cluster, server)
+	}
+}
+
+// forgetCluster 删除被移除集群的 outbox 积压指标
+func forgetCluster(cluster string) {
+	outboxDepth.DeleteLabel("cluster", cluster)
+}
+
+// recordResult 将一次注册或注销的结果计入指标
+func recordResult(result client.RegistrationResult) {
+	for _, server := range result.Servers {
+		if server.Skipped {
+			continue
+		}
+		outcome := "success"
+		switch {
+		case errors.Is(server.Err, client.ErrCircuitOpen):
+			outcome = "circuit_open"
+		case errors.Is(server.Err, client.ErrNotSupported):
+			outcome = "unsupported"
+		case server.Err != nil:
+			outcome = "failure"
+		}
+
+		serverRequests.Inc(server.ServerURL, result.Operation, outcome)
+		serverAttempts.Add(float64(server.Attempts), server.ServerURL, result.Operation)
+		if server.Err == nil {
+			acceptedIDs.Add(float64(len(server.Accepted)), server.ServerURL, result.Operation)
+			lastSuccess.Set(float64(time.Now().Unix()), server.ServerURL, result.Operation)
+		}
+
+		open := 0.0
+		if client.CircuitOpen(server.ServerURL) {
+			open = 1
+		}
+		circuitOpen.Set(open, server.ServerURL)
+	}
+}
+
+// ServeMetrics 在 addr 上提供 /metrics、/healthz 及 /healthz/leader，clusters 不为空时提供 /healthz/clusters；
+// leader 为 nil 表示未启用选主
+func ServeMetrics(addr string, clusters *ClusterManager, leader *LeaderElection) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsRegistry)
+	if clusters != nil {
+		mux.Handle("/healthz/clusters", clusters)
+	}
+	mux.Handle("/healthz/leader", leader)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	return http.ListenAndServe(addr, mux)
+}
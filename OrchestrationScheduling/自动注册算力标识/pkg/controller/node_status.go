@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RegistrationStatus 为节点注册状态的注解，值为 "<原因>: <说明>"，如 "Invalid: label cncos.org/city: expected 4 characters, got \"110\""
+const RegistrationStatus = "cncos.org/registration-status"
+
+// 节点注册状态的原因，同时作为 Event 的 reason
+const (
+	// StatusRegistered 节点的算力标识已上报
+	StatusRegistered = "Registered"
+	// StatusReportFailed 节点的算力标识组装成功，但上报失败
+	StatusReportFailed = "ReportFailed"
+	// StatusInvalid 节点标签不符合算力标识字段要求，节点被跳过
+	StatusInvalid = "Invalid"
+	// StatusNotRequested 节点未打 cncos.org/register=true 标签
+	StatusNotRequested = "NotRequested"
+)
+
+// eventNamespace 为 Node Event 所在的命名空间，与 kubelet 一致
+const eventNamespace = metav1.NamespaceDefault
+
+// eventSource 为 Event 的来源组件
+const eventSource = "resource-controller"
+
+// nodeStatusTimeout 为更新单个节点状态的最长时间
+const nodeStatusTimeout = 10 * time.Second
+
+// nodeStatus 为节点的注册状态
+type nodeStatus struct {
+	reason, message string
+}
+
+func (s nodeStatus) String() string {
+	if s.message == "" {
+		return s.reason
+	}
+	return s.reason + ": " + s.message
+}
+
+// validationStatus 返回协调后即可确定的状态，标签合法且需要注册的节点状态在上报后确定，返回 false
+func validationStatus(resources nodeResources) (nodeStatus, bool) {
+	switch {
+	case len(resources.problems) > 0:
+		return nodeStatus{StatusInvalid, strings.Join(resources.problems, "; ")}, true
+	case !resources.register:
+		return nodeStatus{StatusNotRequested, "label the node with " + Register + "=true to register it"}, true
+	}
+	return nodeStatus{}, false
+}
+
+// reportStatus 返回上报后的节点状态，reportErr 为本次上报的错误
+func reportStatus(resources nodeResources, reportErr error) nodeStatus {
+	var chips int64
+	for _, chip := range resources.chips {
+		chips += chip.count
+	}
+	if reportErr != nil {
+		return nodeStatus{StatusReportFailed, reportErr.Error()}
+	}
+	return nodeStatus{StatusRegistered, fmt.Sprintf("%d chips reported", chips)}
+}
+
+// updateNodeStatus 状态变化时更新节点的 cncos.org/registration-status 注解并记录 Event，
+// 只读模式（print）或关闭 -node-status 时不做任何操作
+func (c *NodeResourceController) updateNodeStatus(ctx context.Context, name string, status nodeStatus) {
+	if !c.options.Register || !c.options.NodeStatus {
+		return
+	}
+	node, err := c.nodeLister.Get(name)
+	if err != nil {
+		return
+	}
+	value := status.String()
+	if node.Annotations[RegistrationStatus] == value {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nodeStatusTimeout)
+	defer cancel()
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{RegistrationStatus: value}},
+	})
+	if _, err := c.clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		fmt.Printf("[Error]Failed to update registration status of node %s: %v\n", name, err)
+		return
+	}
+	c.recordNodeEvent(ctx, name, status)
+}
+
+// recordNodeEvent 在节点上记录 Event，kubectl describe node 可见。Event 只在状态变化时记录，不需要合并
+func (c *NodeResourceController) recordNodeEvent(ctx context.Context, name string, status nodeStatus) {
+	eventType := corev1.EventTypeNormal
+	if status.reason == StatusInvalid || status.reason == StatusReportFailed {
+		eventType = corev1.EventTypeWarning
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", name, now.UnixNano()),
+			Namespace: eventNamespace,
+		},
+		// 与 kubelet 相同，Node 的 UID 取节点名
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: name, UID: types.UID(name)},
+		Reason:         status.reason,
+		Message:        status.message,
+		Source:         corev1.EventSource{Component: eventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+	_, err := c.clientset.CoreV1().Events(eventNamespace).Create(ctx, event, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		fmt.Printf("[Error]Failed to record event on node %s: %v\n", name, err)
+	}
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestUpdateNodeStatusForInvalidLabels(t *testing.T) {
+	var mutex sync.Mutex
+	var patches []string
+	var events []corev1.Event
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/nodes/node-1":
+			patches = append(patches, string(body))
+			w.Write([]byte(`{"kind":"Node","apiVersion":"v1","metadata":{"name":"node-1"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/default/events":
+			var event corev1.Event
+			json.Unmarshal(body, &event)
+			events = append(events, event)
+			w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer apiServer.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: apiServer.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.Register = true
+	c := NewNodeResourceController("member", clientset, nil, options, nil)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+		Register: "true", City: "110", CompanyType: "cp", Company: "20001", ResourceType: "402",
+		ResourceAZ: "501", ServiceType: "02602001601004", StorageCapacity: "123456789",
+	}}}
+	c.informerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node)
+
+	resources := assembleNodeResources(node, staticSources{})
+	status, ok := validationStatus(resources)
+	if !ok || status.reason != StatusInvalid {
+		t.Fatalf("expected an invalid status, got %+v", status)
+	}
+	for _, expected := range []string{`label cncos.org/city: expected 4 characters, got "110"`,
+		`label cncos.org/disk-capacity: expected at most 7 characters, got "123456789"`} {
+		if !strings.Contains(status.message, expected) {
+			t.Errorf("status %q does not mention %q", status.message, expected)
+		}
+	}
+
+	c.updateNodeStatus(context.Background(), "node-1", status)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(patches) != 1 || !strings.Contains(patches[0], RegistrationStatus) {
+		t.Fatalf("unexpected patches %v", patches)
+	}
+	if len(events) != 1 || events[0].Type != corev1.EventTypeWarning || events[0].Reason != StatusInvalid ||
+		events[0].InvolvedObject.Name != "node-1" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
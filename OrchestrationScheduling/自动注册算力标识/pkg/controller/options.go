@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"register-power-resources/pkg/client"
+)
+
+// 上报模式，决定算力标识的粒度
+const (
+	// ReportPerChip 每颗芯片一个算力标识，芯片唯一编号为节点内序号
+	ReportPerChip = "per-chip"
+	// ReportPerNode 节点上每个芯片型号一个算力标识，芯片唯一编号字段为芯片数量
+	ReportPerNode = "per-node"
+	// ReportPerCluster 合并集群内静态字段、网络类型及各芯片型号都相同的节点，计算量、功耗求和，每个芯片型号一个算力标识，芯片唯一编号字段为芯片总数
+	ReportPerCluster = "per-cluster"
+)
+
+// Options 为资源上报服务的运行参数
+type Options struct {
+	// Register 为 false 时只打印组装的算力标识，不向 server 上报
+	Register     bool
+	SyncInterval time.Duration
+	// ReportMode 为 per-chip、per-node 或 per-cluster
+	ReportMode string
+	// FullResyncInterval 为全量同步周期，其间只上报变化的算力标识
+	FullResyncInterval time.Duration
+	// MetricsAddr 为 /metrics、/healthz 及 /healthz/clusters、/healthz/leader 的监听地址，为空时不启动
+	MetricsAddr string
+	// NodeStatus 为 true 时在节点上记录注册状态注解及 Event，需要成员集群中 nodes 的 patch 及 events 的 create 权限
+	NodeStatus bool
+	// ComputeRegistrations 为 true 时从 ComputeRegistration 读取静态字段覆盖，并把组装、上报的结果写入其 status，
+	// 成员集群需要安装 manifests/crds 中的 CRD
+	ComputeRegistrations bool
+	// OutboxDir 为未送达操作的保存目录，每个集群一个子目录，为空时不启用
+	OutboxDir string
+	Retry     client.RetryPolicy
+	// KubeconfigSecrets 为 true 时同时监听 kube-system 中带标签的 Secret，需要其中 Secret 的 list、watch 权限；
+	// 为 false 时只能通过 ConfigMap 的 kubeconfigSecret 按名称引用 Secret
+	KubeconfigSecrets bool
+
+	// LeaderElect 为 true 时通过 Lease 选主，只有 leader 运行集群控制器，需要 coordination.k8s.io 中 leases 的权限
+	LeaderElect             bool
+	LeaderElectionNamespace string
+	LeaderElectionName      string
+	// LeaseDuration 为非 leader 等待 Lease 过期的时间，RenewDeadline 为 leader 续约失败后放弃领导权的时间，
+	// RetryPeriod 为获取、续约的间隔
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+func NewOptions() *Options {
+	return &Options{
+		SyncInterval:       60 * time.Second,
+		ReportMode:         ReportPerChip,
+		NodeStatus:         true,
+		FullResyncInterval: time.Hour,
+		MetricsAddr:        ":9090",
+		Retry:              client.DefaultRetryPolicy(),
+
+		LeaderElect:             true,
+		LeaderElectionNamespace: "cncos-system",
+		LeaderElectionName:      "resource-controller",
+		LeaseDuration:           15 * time.Second,
+		RenewDeadline:           10 * time.Second,
+		RetryPeriod:             2 * time.Second,
+	}
+}
+
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&o.SyncInterval, "sync-interval", o.SyncInterval, "Interval between node resource syncs")
+	fs.StringVar(&o.ReportMode, "report-mode", o.ReportMode,
+		"Granularity of compute ids: per-chip, per-node (one id per chip model on each node, chip count in the unique number) or per-cluster (nodes with the same static fields and chip models merged, compute and power summed)")
+	fs.DurationVar(&o.FullResyncInterval, "full-resync-interval", o.FullResyncInterval,
+		"Interval between full re-registrations, only changes are reported in between; 0 resyncs only on failure or when a server is missing acknowledged ids")
+	fs.StringVar(&o.MetricsAddr, "metrics-addr", o.MetricsAddr, "Address serving /metrics and /healthz endpoints, disabled when empty")
+	fs.BoolVar(&o.NodeStatus, "node-status", o.NodeStatus,
+		"Record each node's registration status in the cncos.org/registration-status annotation and as Node events")
+	fs.BoolVar(&o.ComputeRegistrations, "compute-registrations", o.ComputeRegistrations,
+		"Read static field overrides from ComputeRegistration resources and record assembled ids and report results in their status; the CRD must be installed in member clusters")
+	fs.StringVar(&o.OutboxDir, "outbox-dir", o.OutboxDir,
+		"Directory persisting operations that could not be delivered, replayed in order once servers are reachable; disabled when empty")
+	fs.BoolVar(&o.KubeconfigSecrets, "kubeconfig-secrets", o.KubeconfigSecrets,
+		"Also watch labelled Secrets in kube-system for member cluster credentials; requires list and watch on secrets there")
+	fs.IntVar(&o.Retry.MaxAttempts, "retry-max-attempts", o.Retry.MaxAttempts,
+		"Maximum attempts per server, including the first request")
+	fs.DurationVar(&o.Retry.InitialBackoff, "retry-initial-backoff", o.Retry.InitialBackoff, "Backoff before the first retry")
+	fs.DurationVar(&o.Retry.MaxBackoff, "retry-max-backoff", o.Retry.MaxBackoff, "Upper bound of the exponential backoff")
+	fs.Float64Var(&o.Retry.Jitter, "retry-jitter", o.Retry.Jitter, "Random jitter applied to each backoff, as a fraction")
+	fs.IntVar(&o.Retry.BreakerThreshold, "breaker-threshold", o.Retry.BreakerThreshold,
+		"Consecutive failures before a server's circuit opens, 0 disables circuit breaking")
+	fs.DurationVar(&o.Retry.BreakerCooldown, "breaker-cooldown", o.Retry.BreakerCooldown,
+		"Time a server's circuit stays open before a probe request is allowed")
+	fs.BoolVar(&o.LeaderElect, "leader-elect", o.LeaderElect,
+		"Elect a leader through a Lease so that only one replica runs cluster controllers")
+	fs.StringVar(&o.LeaderElectionNamespace, "leader-election-namespace", o.LeaderElectionNamespace, "Namespace of the leader election Lease")
+	fs.StringVar(&o.LeaderElectionName, "leader-election-name", o.LeaderElectionName, "Name of the leader election Lease")
+	fs.DurationVar(&o.LeaseDuration, "leader-election-lease-duration", o.LeaseDuration,
+		"Time non-leaders wait after the last renewal before taking over the lease")
+	fs.DurationVar(&o.RenewDeadline, "leader-election-renew-deadline", o.RenewDeadline,
+		"Time the leader keeps retrying to renew the lease before giving up leadership; must be less than the lease duration")
+	fs.DurationVar(&o.RetryPeriod, "leader-election-retry-period", o.RetryPeriod, "Interval between attempts to acquire or renew the lease")
+}
+
+func (o *Options) Validate() error {
+	switch o.ReportMode {
+	case ReportPerChip, ReportPerNode, ReportPerCluster:
+	default:
+		return fmt.Errorf("unknown report mode %q, expected %s, %s or %s", o.ReportMode, ReportPerChip, ReportPerNode, ReportPerCluster)
+	}
+	if o.LeaderElect {
+		if o.RetryPeriod <= 0 || o.RenewDeadline <= o.RetryPeriod || o.LeaseDuration <= o.RenewDeadline {
+			return fmt.Errorf("leader election durations must satisfy lease duration (%v) > renew deadline (%v) > retry period (%v) > 0",
+				o.LeaseDuration, o.RenewDeadline, o.RetryPeriod)
+		}
+		if o.LeaderElectionNamespace == "" || o.LeaderElectionName == "" {
+			return fmt.Errorf("leader election namespace and name must not be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"register-power-resources/pkg/apis"
+	"register-power-resources/pkg/client"
+)
+
+// nodeNameIndex 按 spec.nodeName 索引 ComputeRegistration，集群级资源的索引值为空串
+const nodeNameIndex = "nodeName"
+
+// registrations 通过 dynamic client 读写成员集群中的 ComputeRegistration
+type registrations struct {
+	client   dynamic.NamespaceableResourceInterface
+	factory  dynamicinformer.DynamicSharedInformerFactory
+	informer cache.SharedIndexInformer
+}
+
+// newRegistrations 监听 ComputeRegistration，spec 变化时以节点名调用 onChange，集群级资源变化时 nodeName 为空
+func newRegistrations(dynamicClient dynamic.Interface, resync time.Duration, onChange func(nodeName string)) *registrations {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, resync)
+	informer := factory.ForResource(apis.ComputeRegistrationResource).Informer()
+	informer.AddIndexers(cache.Indexers{nodeNameIndex: func(obj interface{}) ([]string, error) {
+		return []string{specNodeName(obj)}, nil
+	}})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { onChange(specNodeName(obj)) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// 只关心 spec 变化，控制器自己写入的 status 不触发协调
+			if oldObj.(*unstructured.Unstructured).GetGeneration() == newObj.(*unstructured.Unstructured).GetGeneration() {
+				return
+			}
+			if specNodeName(oldObj) != specNodeName(newObj) {
+				onChange(specNodeName(oldObj))
+			}
+			onChange(specNodeName(newObj))
+		},
+		DeleteFunc: func(obj interface{}) { onChange(specNodeName(obj)) },
+	})
+	return &registrations{
+		client:   dynamicClient.Resource(apis.ComputeRegistrationResource),
+		factory:  factory,
+		informer: informer,
+	}
+}
+
+func specNodeName(obj interface{}) string {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	name, _, _ := unstructured.NestedString(u.Object, "spec", "nodeName")
+	return name
+}
+
+// HasSynced 表示 ComputeRegistration 缓存已完成首次同步，未启用（nil）时视为已同步
+func (r *registrations) HasSynced() bool {
+	return r == nil || r.informer.HasSynced()
+}
+
+// forNode 返回节点的 ComputeRegistration，nodeName 为空时返回集群级资源；存在多个时按名称取第一个
+func (r *registrations) forNode(nodeName string) *apis.ComputeRegistration {
+	if r == nil {
+		return nil
+	}
+	objs, err := r.informer.GetIndexer().ByIndex(nodeNameIndex, nodeName)
+	if err != nil || len(objs) == 0 {
+		return nil
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].(*unstructured.Unstructured).GetName() < objs[j].(*unstructured.Unstructured).GetName()
+	})
+
+	var registration apis.ComputeRegistration
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objs[0].(*unstructured.Unstructured).Object, &registration); err != nil {
+		fmt.Printf("[Error]Invalid ComputeRegistration %s: %v\n", objs[0].(*unstructured.Unstructured).GetName(), err)
+		return nil
+	}
+	return &registration
+}
+
+// create 为节点创建 ComputeRegistration，节点删除后由垃圾回收一并删除
+func (r *registrations) create(ctx context.Context, node *corev1.Node) (*apis.ComputeRegistration, error) {
+	registration := &apis.ComputeRegistration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apis.ComputeRegistrationResource.GroupVersion().String(),
+			Kind:       "ComputeRegistration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: node.Name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Node",
+				Name:       node.Name,
+				UID:        node.UID,
+			}},
+		},
+		Spec: apis.ComputeRegistrationSpec{NodeName: node.Name},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(registration)
+	if err != nil {
+		return nil, err
+	}
+	created, err := r.client.Create(ctx, &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(created.Object, registration); err != nil {
+		return nil, err
+	}
+	return registration, nil
+}
+
+// updateStatus 状态变化时写入 status 子资源，lastTransitionTime 等时间按秒比较
+func (r *registrations) updateStatus(ctx context.Context, registration *apis.ComputeRegistration, status apis.ComputeRegistrationStatus) error {
+	if apiequality.Semantic.DeepEqual(registration.Status, status) {
+		return nil
+	}
+	updated := *registration
+	updated.Status = status
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&updated)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.UpdateStatus(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+	return err
+}
+
+// updateRegistrations 为需要注册的节点创建 ComputeRegistration，并按本次上报的结果更新节点及集群级资源的状态；
+// reported 为本次上报的全部算力标识
+func (c *NodeResourceController) updateRegistrations(ctx context.Context, nodes map[string]nodeResources, reported []string) {
+	if c.registrations == nil || !c.options.Register {
+		return
+	}
+	servers := c.reporter.ServerStatuses()
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		resources := nodes[name]
+		registration := c.registrations.forNode(name)
+		if registration == nil {
+			if !resources.register {
+				continue
+			}
+			node, err := c.nodeLister.Get(name)
+			if err != nil {
+				continue
+			}
+			createCtx, cancel := context.WithTimeout(ctx, nodeStatusTimeout)
+			registration, err = c.registrations.create(createCtx, node)
+			cancel()
+			if err != nil {
+				if !apierrors.IsAlreadyExists(err) {
+					fmt.Printf("[Error]Failed to create ComputeRegistration for node %s: %v\n", name, err)
+				}
+				continue
+			}
+		}
+
+		ids := nodeReportedIDs(c.options.ReportMode, nodes, name)
+		ready := metav1.Condition{Type: apis.ConditionReady, Status: metav1.ConditionTrue, Reason: "Assembled",
+			Message: fmt.Sprintf("%d compute ids assembled", len(ids))}
+		if status, ok := validationStatus(resources); ok {
+			ready = metav1.Condition{Type: apis.ConditionReady, Status: metav1.ConditionFalse, Reason: status.reason, Message: status.message}
+		}
+		c.updateRegistrationStatus(ctx, registration, c.registrationStatus(registration, ids, ready, servers))
+	}
+
+	if registration := c.registrations.forNode(""); registration != nil {
+		ready := metav1.Condition{Type: apis.ConditionReady, Status: metav1.ConditionTrue, Reason: "Assembled",
+			Message: fmt.Sprintf("%d compute ids assembled from %d nodes", len(reported), len(nodes))}
+		status := c.registrationStatus(registration, reported, ready, servers)
+		status.ComputeIDs = nil
+		c.updateRegistrationStatus(ctx, registration, status)
+	}
+}
+
+func (c *NodeResourceController) updateRegistrationStatus(ctx context.Context, registration *apis.ComputeRegistration,
+	status apis.ComputeRegistrationStatus) {
+	// 每次调用单独计时，节点较多时不会因共用的超时导致后面的节点全部失败
+	ctx, cancel := context.WithTimeout(ctx, nodeStatusTimeout)
+	defer cancel()
+	err := c.registrations.updateStatus(ctx, registration, status)
+	// 缓存尚未收到上次的更新时会冲突，下次上报时重试
+	if err != nil && !apierrors.IsConflict(err) {
+		fmt.Printf("[Error]Failed to update status of ComputeRegistration %s: %v\n", registration.Name, err)
+	}
+}
+
+// registrationStatus 组装 ComputeRegistration 的状态：Registered 表示 ids 已被全部 server 实际接收，Error 为标签不合法或上报失败
+func (c *NodeResourceController) registrationStatus(registration *apis.ComputeRegistration, ids []string,
+	ready metav1.Condition, servers []client.ServerStatus) apis.ComputeRegistrationStatus {
+	status := apis.ComputeRegistrationStatus{
+		ObservedGeneration: registration.Generation,
+		ComputeIDs:         ids,
+		ComputeIDCount:     len(ids),
+		Conditions:         append([]metav1.Condition(nil), registration.Status.Conditions...),
+	}
+
+	var pending, failed []string
+	for _, server := range servers {
+		report := apis.ServerReportStatus{Server: server.ServerURL}
+		if !server.LastReport.IsZero() {
+			// status 中的时间精确到秒，截断后才能与读回的状态比较
+			t := metav1.NewTime(server.LastReport.Truncate(time.Second))
+			report.LastReportTime = &t
+		}
+		if server.LastError != nil {
+			report.LastError = server.LastError.Error()
+			failed = append(failed, server.ServerURL+": "+report.LastError)
+		}
+		if !c.reporter.Acknowledged(server.ServerURL, ids) {
+			pending = append(pending, server.ServerURL)
+		}
+		status.Servers = append(status.Servers, report)
+	}
+
+	registered := metav1.Condition{Type: apis.ConditionRegistered, Status: metav1.ConditionTrue, Reason: "Acknowledged",
+		Message: fmt.Sprintf("acknowledged by %d servers", len(servers))}
+	switch {
+	case ready.Status != metav1.ConditionTrue:
+		registered = metav1.Condition{Type: apis.ConditionRegistered, Status: metav1.ConditionFalse, Reason: "NotReady"}
+	case len(servers) == 0:
+		registered = metav1.Condition{Type: apis.ConditionRegistered, Status: metav1.ConditionFalse, Reason: "NoServers",
+			Message: "no servers configured"}
+	case len(pending) > 0:
+		registered = metav1.Condition{Type: apis.ConditionRegistered, Status: metav1.ConditionFalse, Reason: "Pending",
+			Message: "not acknowledged by " + strings.Join(pending, ", ")}
+	}
+
+	errorCondition := metav1.Condition{Type: apis.ConditionError, Status: metav1.ConditionFalse, Reason: "NoError"}
+	switch {
+	case ready.Reason == StatusInvalid:
+		errorCondition = metav1.Condition{Type: apis.ConditionError, Status: metav1.ConditionTrue, Reason: StatusInvalid, Message: ready.Message}
+	case len(failed) > 0:
+		errorCondition = metav1.Condition{Type: apis.ConditionError, Status: metav1.ConditionTrue, Reason: StatusReportFailed,
+			Message: strings.Join(failed, "; ")}
+	}
+
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	for _, condition := range []metav1.Condition{ready, registered, errorCondition} {
+		condition.ObservedGeneration = registration.Generation
+		condition.LastTransitionTime = now
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+	return status
+}
+
+// nodeReportedIDs 返回节点在本次上报中对应的算力标识；集群模式下为该节点所在合并结果的标识
+func nodeReportedIDs(mode string, nodes map[string]nodeResources, name string) []string {
+	resources := nodes[name]
+	if !resources.register {
+		return nil
+	}
+	if mode != ReportPerCluster {
+		ids, _ := computeIDs(mode, map[string]nodeResources{name: resources})
+		return ids
+	}
+
+	// 与该节点合并的节点共同组成的标识
+	key := nodeShapeKey(resources)
+	merged := map[string]nodeResources{}
+	for other, r := range nodes {
+		if r.register && nodeShapeKey(r) == key {
+			merged[other] = r
+		}
+	}
+	ids, _ := computeIDs(mode, merged)
+	return ids
+}
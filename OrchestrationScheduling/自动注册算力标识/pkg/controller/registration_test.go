@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"register-power-resources/pkg/apis"
+)
+
+func TestComputeRegistrationOverridesAndStatus(t *testing.T) {
+	var mutex sync.Mutex
+	var created, updated *apis.ComputeRegistration
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var registration apis.ComputeRegistration
+		json.Unmarshal(body, &registration)
+		registration.ResourceVersion = "1"
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/cncos.org/v1alpha1/computeregistrations":
+			created = &registration
+		case r.Method == http.MethodPut && r.URL.Path == "/apis/cncos.org/v1alpha1/computeregistrations/node-1/status":
+			updated = &registration
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(registration)
+	}))
+	defer apiServer.Close()
+
+	config := &rest.Config{Host: apiServer.URL}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := NewOptions()
+	options.Register = true
+	c := NewNodeResourceController("member", clientset, dynamicClient, options, nil)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", UID: "uid-1", Labels: map[string]string{
+		Register: "true", City: "1101", CompanyType: "cp", Company: "20001", ResourceType: "402",
+		ResourceAZ: "501", ServiceType: "02602001601004", CPUChipNumber: "2", CPUChipType: "00001",
+	}}}
+	c.informerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node)
+
+	// 节点的 ComputeRegistration 优先于编码标签
+	override := &apis.ComputeRegistration{Spec: apis.ComputeRegistrationSpec{NodeName: "node-1", City: "上海"}}
+	resources := assembleNodeResources(node, staticSources{node: override})
+	if len(resources.chips) != 1 || resources.chips[0].info.City != "3101" {
+		t.Fatalf("expected city overridden to 3101, got %+v", resources.chips)
+	}
+
+	nodes := map[string]nodeResources{"node-1": resources}
+	reported, _ := computeIDs(options.ReportMode, nodes)
+	c.updateRegistrations(context.Background(), nodes, reported)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if created == nil || created.Spec.NodeName != "node-1" || len(created.OwnerReferences) != 1 ||
+		created.OwnerReferences[0].UID != "uid-1" {
+		t.Fatalf("unexpected created ComputeRegistration %+v", created)
+	}
+	if updated == nil || len(updated.Status.ComputeIDs) != 2 || updated.Status.ComputeIDCount != 2 {
+		t.Fatalf("unexpected status %+v", updated)
+	}
+	if !meta.IsStatusConditionTrue(updated.Status.Conditions, apis.ConditionReady) ||
+		meta.IsStatusConditionTrue(updated.Status.Conditions, apis.ConditionRegistered) ||
+		meta.IsStatusConditionTrue(updated.Status.Conditions, apis.ConditionError) {
+		t.Fatalf("unexpected conditions %+v", updated.Status.Conditions)
+	}
+}
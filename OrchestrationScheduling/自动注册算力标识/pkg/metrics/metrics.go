@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector 以 Prometheus 文本格式输出一组指标
+type Collector interface {
+	Write(w io.Writer)
+}
+
+type vec struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+
+	mutex  sync.Mutex
+	values map[string]float64
+	labels map[string][]string
+}
+
+func newVec(name, help, metricType string, labelNames []string) *vec {
+	return &vec{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+}
+
+func (v *vec) add(delta float64, labelValues []string) {
+	key := v.key(labelValues)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if _, ok := v.labels[key]; !ok {
+		v.labels[key] = append([]string(nil), labelValues...)
+	}
+	v.values[key] += delta
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+	key := v.key(labelValues)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if _, ok := v.labels[key]; !ok {
+		v.labels[key] = append([]string(nil), labelValues...)
+	}
+	v.values[key] = value
+}
+
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (v *vec) Write(w io.Writer) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.metricType)
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labelNames, v.labels[key]),
+			strconv.FormatFloat(v.values[key], 'g', -1, 64))
+	}
+}
+
+// CounterVec 为只增不减的计数器
+type CounterVec struct {
+	*vec
+}
+
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{vec: newVec(name, help, "counter", labelNames)}
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metric %s: counter cannot decrease", c.name))
+	}
+	c.add(delta, labelValues)
+}
+
+// GaugeVec 为可任意设置的瞬时值
+type GaugeVec struct {
+	*vec
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{vec: newVec(name, help, "gauge", labelNames)}
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.add(delta, labelValues)
+}
+
+// DeleteLabel 删除标签 name 取值为 value 的全部标签组合，如被移除集群的指标，返回删除的数量
+func (g *GaugeVec) DeleteLabel(name, value string) int {
+	index := -1
+	for i, labelName := range g.labelNames {
+		if labelName == name {
+			index = i
+		}
+	}
+	if index < 0 {
+		return 0
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	deleted := 0
+	for key, labelValues := range g.labels {
+		if labelValues[index] == value {
+			delete(g.labels, key)
+			delete(g.values, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Reset 清空所有标签组合，用于每次采集前重新计算的指标
+func (g *GaugeVec) Reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.values = make(map[string]float64)
+	g.labels = make(map[string][]string)
+}
+
+// Registry 汇总多个 Collector，并在采集前执行回调刷新指标
+type Registry struct {
+	mutex      sync.Mutex
+	collectors []Collector
+	hooks      []func()
+	// collect 使并发采集依次执行回调及输出，避免回调重置、累加指标时相互交错
+	collect sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// OnCollect 注册在每次采集前调用的回调
+func (r *Registry) OnCollect(hook func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.Lock()
+	hooks := append([]func(){}, r.hooks...)
+	collectors := append([]Collector{}, r.collectors...)
+	r.mutex.Unlock()
+
+	r.collect.Lock()
+	defer r.collect.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	for _, collector := range collectors {
+		collector.Write(w)
+	}
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names))
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[i])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
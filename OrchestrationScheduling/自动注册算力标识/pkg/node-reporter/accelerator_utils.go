@@ -0,0 +1,131 @@
+package node_reporter
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"register-power-resources/pkg/apis"
+)
+
+// acceleratorDetector 按 lspci 输出识别一种加速卡，pattern 的第一个分组为设备型号
+type acceleratorDetector struct {
+	class   string
+	pattern *regexp.Regexp
+	// model 将设备型号转换为 chip-codes 中的型号名
+	model func(string) string
+}
+
+// ascendModels 为昇腾的 PCI 设备号对应的型号
+var ascendModels = map[string]string{
+	"d100": "HUAWEI_Ascend_310",
+	"d801": "HUAWEI_Ascend_910",
+}
+
+var acceleratorDetectors = []acceleratorDetector{
+	// 华为昇腾，如 "Processing accelerators: Huawei Technologies Co., Ltd. Device d801 (rev 20)"
+	{"npu", regexp.MustCompile(`Huawei Technologies Co\., Ltd\. Device (d100|d801)`),
+		func(device string) string { return ascendModels[device] }},
+	// 寒武纪 MLU，如 "Processing accelerators: Cambricon Technologies Corporation Device 0290"
+	{"npu", regexp.MustCompile(`Cambricon .*?(?:Device 0*|MLU)(\d+)`),
+		func(device string) string { return "MLU" + device }},
+	// 算能 BM1684，如 "Processing accelerators: Bitmain Technologies Inc. BM1684, Sophon Series Deep Learning Accelerator"
+	{"asic", regexp.MustCompile(`(?:Bitmain|Sophgo|SOPHGO) .*?(BM1684X?)\b`),
+		func(device string) string { return device }},
+}
+
+// acceleratorChip 为节点上某类加速卡中数量最多的型号
+type acceleratorChip struct {
+	chipType, chipModel string
+	chipNum             int
+}
+
+// getAcceleratorChipInfo 返回 lspci 中识别到的各类加速卡，键为芯片类别（见 apis.ChipClasses）。
+// 型号不在 chip-codes 中时记为 Other
+func getAcceleratorChipInfo(chipCodes map[string]string) (map[string]acceleratorChip, error) {
+	cmd := exec.Command("lspci")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running lspci: %v", err)
+	}
+	return detectAccelerators(strings.Split(out.String(), "\n"), chipCodes), nil
+}
+
+func detectAccelerators(lines []string, chipCodes map[string]string) map[string]acceleratorChip {
+	models := make(map[string]map[string]int)
+	for _, line := range lines {
+		for _, detector := range acceleratorDetectors {
+			matches := detector.pattern.FindStringSubmatch(line)
+			if len(matches) < 2 {
+				continue
+			}
+			if models[detector.class] == nil {
+				models[detector.class] = make(map[string]int)
+			}
+			models[detector.class][detector.model(matches[1])]++
+			break
+		}
+	}
+
+	chips := make(map[string]acceleratorChip)
+	for _, class := range apis.ChipClasses {
+		var chip acceleratorChip
+		var maxModel string
+		for model, count := range models[class.Name] {
+			if count > chip.chipNum || (count == chip.chipNum && model < maxModel) {
+				chip.chipNum, maxModel = count, model
+			}
+		}
+		if chip.chipNum == 0 {
+			continue
+		}
+		chip.chipType = class.ChipType
+		chip.chipModel = chipCodes["Other"]
+		if code, ok := chipCodes[maxModel]; ok {
+			chip.chipModel = code
+		}
+		if chip.chipModel == "" {
+			chip.chipModel = "11111111"
+		}
+		fmt.Printf("%s %s: %d\n", class.Name, maxModel, chip.chipNum)
+		chips[class.Name] = chip
+	}
+	return chips
+}
+
+// updateAcceleratorLabels 写入识别到的加速卡标签。上次识别到、本次未识别到的类别（如加速卡已拔出）芯片数量置为 0，
+// 从未识别到的类别保留节点上已有的标签，可手工填写
+func updateAcceleratorLabels(node *corev1.Node, accelerators map[string]acceleratorChip) {
+	previous := make(map[string]bool)
+	for _, class := range strings.Split(node.Annotations[DetectedAccelerators], ",") {
+		previous[class] = true
+	}
+
+	var detected []string
+	for _, class := range apis.ChipClasses {
+		labels := class.Labels()
+		chip, ok := accelerators[class.Name]
+		if !ok {
+			if previous[class.Name] {
+				fmt.Printf("%s no longer detected\n", class.Name)
+				node.Labels[labels.Number] = "0"
+			}
+			continue
+		}
+		node.Labels[labels.Type] = chip.chipType
+		node.Labels[labels.Model] = chip.chipModel
+		node.Labels[labels.Number] = strconv.Itoa(chip.chipNum)
+		detected = append(detected, class.Name)
+	}
+
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[DetectedAccelerators] = strings.Join(detected, ",")
+}
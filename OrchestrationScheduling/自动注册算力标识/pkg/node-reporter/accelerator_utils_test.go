@@ -0,0 +1,76 @@
+package node_reporter
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"register-power-resources/pkg/apis"
+)
+
+var testChipCodes = map[string]string{
+	"HUAWEI_Ascend_910": "00000101",
+	"BM1684":            "00000201",
+	"Other":             "99999999",
+}
+
+func TestDetectAccelerators(t *testing.T) {
+	const (
+		ascend910 = "3b:00.0 Processing accelerators: Huawei Technologies Co., Ltd. Device d801 (rev 20)"
+		ascend310 = "3c:00.0 Processing accelerators: Huawei Technologies Co., Ltd. Device d100 (rev 20)"
+		mlu       = "3d:00.0 Processing accelerators: Cambricon Technologies Corporation Device 0290"
+		bm1684    = "3e:00.0 Processing accelerators: Bitmain Technologies Inc. BM1684, Sophon Series Deep Learning Accelerator"
+		nic       = "18:00.0 Ethernet controller: Mellanox Technologies MT27800 Family [ConnectX-5]"
+	)
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		want  map[string]acceleratorChip
+	}{
+		{"none", []string{nic, ""}, map[string]acceleratorChip{}},
+		{"ascend", []string{ascend910, ascend910, nic},
+			map[string]acceleratorChip{"npu": {apis.ChipTypeNPU, "00000101", 2}}},
+		// 同一类别取数量最多的型号，不在 chip-codes 中的型号记为 Other
+		{"most common model", []string{ascend910, mlu, mlu},
+			map[string]acceleratorChip{"npu": {apis.ChipTypeNPU, "99999999", 2}}},
+		// 数量相同时取名称较小的型号
+		{"tie", []string{ascend310, ascend910},
+			map[string]acceleratorChip{"npu": {apis.ChipTypeNPU, "99999999", 1}}},
+		{"sophon", []string{bm1684, ascend910},
+			map[string]acceleratorChip{
+				"npu":  {apis.ChipTypeNPU, "00000101", 1},
+				"asic": {apis.ChipTypeASIC, "00000201", 1},
+			}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectAccelerators(tc.lines, testChipCodes); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	// chip-codes 中没有 Other 时使用 11111111
+	got := detectAccelerators([]string{mlu}, map[string]string{})
+	if got["npu"].chipModel != "11111111" {
+		t.Fatalf("unexpected model without Other code: %+v", got)
+	}
+}
+
+func TestUpdateAcceleratorLabelsClearsRemovedCards(t *testing.T) {
+	npu := apis.ChipClass{Name: "npu"}.Labels()
+	dpu := apis.ChipClass{Name: "dpu"}.Labels()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{dpu.Number: "2"}}}
+
+	updateAcceleratorLabels(node, map[string]acceleratorChip{"npu": {apis.ChipTypeNPU, "00000101", 8}})
+	if node.Labels[npu.Number] != "8" || node.Annotations[DetectedAccelerators] != "npu" {
+		t.Fatalf("unexpected node after detection: %v %v", node.Labels, node.Annotations)
+	}
+
+	// 加速卡拔出后数量置为 0，手工填写的 dpu 标签不受影响
+	updateAcceleratorLabels(node, map[string]acceleratorChip{})
+	if node.Labels[npu.Number] != "0" || node.Labels[dpu.Number] != "2" || node.Annotations[DetectedAccelerators] != "" {
+		t.Fatalf("unexpected node after removal: %v %v", node.Labels, node.Annotations)
+	}
+}
@@ -27,6 +27,8 @@ const (
 	NetworkAddress      = "cncos.org/network-address"
 	CPUCapacity         = "cncos.org/cpu-capacity"
 	MemoryCapacity      = "cncos.org/memory-capacity"
+	// DetectedAccelerators 记录上次扫描识别到的加速卡类别，以逗号分隔
+	DetectedAccelerators = "cncos.org/detected-accelerators"
 )
 
 type NodeReporter struct {
@@ -83,6 +85,14 @@ func (r *NodeReporter) UpdateNodeLabels() error {
 	node.Labels[GPUChipModel] = gpuChipModel
 	node.Labels[GPUChipNumber] = strconv.Itoa(gpuChipNum)
 
+	// NPU、ASIC 等加速卡的类型、型号、数量；计算量、能耗需手工填写。lspci 失败时不修改已有标签
+	accelerators, err := getAcceleratorChipInfo(configMap.Data)
+	if err != nil {
+		fmt.Printf("Error detecting accelerators: %v\n", err)
+	} else {
+		updateAcceleratorLabels(node, accelerators)
+	}
+
 	// 计算量、能耗
 	node.Labels[CPUComputeCapacity] = fmt.Sprintf("%04d", int64(getCPUChipFlops()/10))
 	node.Labels[CPUPowerConsumption] = fmt.Sprintf("%05d", int64(getCPUChipFlops()/10/220))
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+const (
+	AuditActionRegister   = "register"
+	AuditActionUpdate     = "update"
+	AuditActionUnregister = "unregister"
+	AuditActionExpire     = "expire"
+)
+
+// 审计查询默认及最多返回的事件数，查询时只在内存中保留最近的 limit 条
+const (
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 1000
+)
+
+// AuditEvent 记录一次注册表变更，Before、After 为变更前后的算力标识
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	ID       string    `json:"id"`
+	Caller   string    `json:"caller,omitempty"`
+	SourceIP string    `json:"source_ip,omitempty"`
+	Before   string    `json:"before,omitempty"`
+	After    string    `json:"after,omitempty"`
+}
+
+func (e *AuditEvent) matches(id string) bool {
+	return id == "" || e.ID == id || e.Before == id || e.After == id
+}
+
+// AuditLog 以 JSON Lines 追加写入审计事件，文件超过 maxSize 后轮转，保留 maxBackups 个历史文件
+type AuditLog struct {
+	// mutex 保护当前文件的追加；rotation 在轮转时持有写锁、查询时持有读锁，查询只阻塞轮转，不阻塞追加
+	mutex    sync.Mutex
+	rotation sync.RWMutex
+
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func NewAuditLog(path string, maxSize int64, maxBackups int) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("error creating audit log directory: %v", err)
+	}
+
+	l := &AuditLog{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error reading audit log: %v", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *AuditLog) Record(event AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mutex.Lock()
+	if l.needsRotation(len(data)) {
+		// 等待进行中的查询结束后再轮转，等待期间释放 mutex，其他事件仍可追加
+		l.mutex.Unlock()
+		l.rotation.Lock()
+		defer l.rotation.Unlock()
+		l.mutex.Lock()
+		if l.needsRotation(len(data)) {
+			if err := l.rotate(); err != nil {
+				l.mutex.Unlock()
+				return err
+			}
+		}
+	}
+	defer l.mutex.Unlock()
+
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+func (l *AuditLog) needsRotation(n int) bool {
+	return l.maxSize > 0 && l.size+int64(n) > l.maxSize && l.size > 0
+}
+
+// rotate 将 audit.log 依次重命名为 audit.log.1、audit.log.2……，调用方需持有 mutex 及 rotation 的写锁
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(l.backupPath(l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		os.Rename(l.backupPath(i), l.backupPath(i+1))
+	}
+	if l.maxBackups > 0 {
+		if err := os.Rename(l.path, l.backupPath(1)); err != nil {
+			return fmt.Errorf("error rotating audit log: %v", err)
+		}
+	} else if err := os.Truncate(l.path, 0); err != nil {
+		return fmt.Errorf("error truncating audit log: %v", err)
+	}
+
+	return l.open()
+}
+
+func (l *AuditLog) backupPath(i int) string {
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// Query 按时间顺序返回与 id 相关的最近 limit 条事件，id 为空时不按标识筛选；limit <= 0 时返回全部事件。
+// 扫描时以环形缓冲只保留最近 limit 条，内存占用与日志大小无关
+func (l *AuditLog) Query(id string, limit int) ([]AuditEvent, error) {
+	l.rotation.RLock()
+	defer l.rotation.RUnlock()
+
+	files := []string{}
+	for i := l.maxBackups; i >= 1; i-- {
+		files = append(files, l.backupPath(i))
+	}
+	files = append(files, l.path)
+
+	events := []AuditEvent{}
+	// next 为环形缓冲中下一条事件的写入位置，缓冲已满时即最早的事件
+	next := 0
+	for _, path := range files {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event AuditEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			if !event.matches(id) {
+				continue
+			}
+			if limit > 0 && len(events) == limit {
+				events[next] = event
+				next = (next + 1) % limit
+			} else {
+				events = append(events, event)
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(events[next:], events[:next]...), nil
+}
+
+func (l *AuditLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+var auditLog *AuditLog
+
+// EnableAuditLog 开启注册表变更审计，未调用时不记录审计事件
+func EnableAuditLog(path string, maxSize int64, maxBackups int) error {
+	l, err := NewAuditLog(path, maxSize, maxBackups)
+	if err != nil {
+		return err
+	}
+	auditLog = l
+	return nil
+}
+
+func recordAudit(r *http.Request, action string, before, after *apis.NodeResourceInfo) {
+	if auditLog == nil {
+		return
+	}
+
+	event := AuditEvent{Time: time.Now().UTC(), Action: action}
+	if r != nil {
+		event.Caller = callerIdentity(r)
+		event.SourceIP = sourceIP(r)
+	}
+	if before != nil {
+		event.ID = before.ID
+		event.Before = before.ID
+	}
+	if after != nil {
+		event.ID = after.ID
+		event.After = after.ID
+	}
+
+	if err := auditLog.Record(event); err != nil {
+		fmt.Printf("Error writing audit event: %v\n", err)
+	}
+}
+
+// anonymousCaller 为未出示已校验客户端证书的调用方
+const anonymousCaller = "anonymous"
+
+// callerIdentity 只取已校验的客户端证书 CN。请求头可被任意调用方伪造，不作为身份
+func callerIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		if cn := r.TLS.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+	return anonymousCaller
+}
+
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// addResource 注册资源并记录 register 或 update 审计事件，标识未变化的刷新不记录
+func addResource(r *http.Request, resource *apis.NodeResourceInfo) {
+	previous := registry.AddResource(resource)
+	trackOwner(r, resource, previous)
+	switch {
+	case previous == nil:
+		recordChange(r, AuditActionRegister, nil, resource)
+	case previous.ID != resource.ID:
+		recordChange(r, AuditActionUpdate, previous, resource)
+	}
+}
+
+func deleteResource(r *http.Request, id string) bool {
+	resource, ok := registry.DeleteResource(id)
+	if ok {
+		untrackOwner(id)
+		recordChange(r, AuditActionUnregister, resource, nil)
+	}
+	return ok
+}
+
+// StartExpiry 定期移除超过 ttl 未重新上报的资源，ttl <= 0 时不启用
+func StartExpiry(ctx context.Context, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	interval := ttl / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, resource := range registry.ExpireBefore(now.Add(-ttl)) {
+					fmt.Printf("[Info]Resource %s expired\n", resource.ID)
+					recordChange(nil, AuditActionExpire, resource, nil)
+				}
+			}
+		}
+	}()
+}
+
+func QueryAudit(w http.ResponseWriter, r *http.Request) {
+	if auditLog == nil {
+		http.Error(w, "audit log is not enabled", http.StatusNotFound)
+		return
+	}
+
+	// 非 admin 客户端只能按本租户的算力标识查询
+	id := r.URL.Query().Get("id")
+	if scope := requestScope(r); !scope.all && (id == "" || !scope.allows(id)) {
+		writeForbidden(w, id)
+		return
+	}
+
+	limit := defaultAuditQueryLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxAuditQueryLimit {
+			http.Error(w, fmt.Sprintf("invalid limit, expected 1 to %d", maxAuditQueryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := auditLog.Query(id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
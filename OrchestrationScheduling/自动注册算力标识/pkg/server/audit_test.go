@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+func TestAuditLogRotationAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	l, err := NewAuditLog(path, 400, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := l.Record(AuditEvent{Action: AuditActionRegister, ID: fmt.Sprintf("id-%02d", i%4)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		if info, err := os.Stat(name); err != nil || info.Size() > 400 {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("more than 2 backups kept: %v", err)
+	}
+
+	// 按时间顺序返回，跨越轮转文件，最早的事件已被丢弃
+	events, err := l.Query("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || len(events) >= 20 || events[len(events)-1].ID != "id-03" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	events, _ = l.Query("id-01", 2)
+	if len(events) != 2 || events[0].ID != "id-01" || events[1].ID != "id-01" {
+		t.Fatalf("unexpected events for id-01 %+v", events)
+	}
+	// 环形缓冲保留最近的 limit 条，仍按时间顺序返回
+	all, _ := l.Query("", 0)
+	events, _ = l.Query("", 3)
+	if !reflect.DeepEqual(events, all[len(all)-3:]) {
+		t.Fatalf("expected the last 3 events %+v, got %+v", all[len(all)-3:], events)
+	}
+
+	// 查询期间追加不被阻塞
+	l.rotation.RLock()
+	done := make(chan error, 1)
+	go func() { done <- l.Record(AuditEvent{Action: AuditActionExpire, ID: "id-99"}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("append blocked by query")
+	}
+	l.rotation.RUnlock()
+}
+
+func TestImportReplaceAuditsUpdates(t *testing.T) {
+	withLimits(t, nil)
+	l, err := NewAuditLog(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog = l
+	defer func() {
+		l.Close()
+		auditLog = nil
+	}()
+
+	kept := matchResourceID(1, 100, 500, apis.NetworkTypeIB, "00001")
+	old := matchResourceID(1, 100, 500, apis.NetworkTypeIB, "00002")
+	removed := matchResourceID(1, 100, 500, apis.NetworkTypeIB, "00003")
+	for _, id := range []string{kept, old, removed} {
+		registry.AddResource(apis.ParseResourceInfo(id))
+	}
+	// 同一芯片容量变化
+	updated := matchResourceID(1, 200, 500, apis.NetworkTypeIB, "00002")
+	added := matchResourceID(2, 100, 500, apis.NetworkTypeIB, "00001")
+
+	var body strings.Builder
+	resources := []*apis.NodeResourceInfo{apis.ParseResourceInfo(kept), apis.ParseResourceInfo(updated), apis.ParseResourceInfo(added)}
+	if err := WriteSnapshot(&body, SnapshotFormatJSONL, resources); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	ImportSnapshot(w, httptest.NewRequest(http.MethodPost, "/v1/snapshot?mode=replace", strings.NewReader(body.String())))
+	if w.Code != http.StatusOK {
+		t.Fatalf("import failed: %d %s", w.Code, w.Body)
+	}
+
+	events, err := l.Query("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]AuditEvent{}
+	for _, event := range events {
+		actions[event.Action] = event
+	}
+	if len(events) != 3 ||
+		actions[AuditActionUpdate].Before != old || actions[AuditActionUpdate].After != updated ||
+		actions[AuditActionUnregister].Before != removed ||
+		actions[AuditActionRegister].After != added {
+		t.Fatalf("unexpected audit events %+v", events)
+	}
+}
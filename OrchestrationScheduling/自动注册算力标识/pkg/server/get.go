@@ -8,17 +8,29 @@ import (
 	"register-power-resources/pkg/apis"
 )
 
-func GetResource(w http.ResponseWriter, r *http.Request) {
+func lookupResource(w http.ResponseWriter, r *http.Request) (*apis.NodeResourceInfo, bool) {
 	id := mux.Vars(r)["id"]
+	if !requestScope(r).allows(id) {
+		writeForbidden(w, id)
+		return nil, false
+	}
 
-	registry.RLock()
-	resource, ok := registry.Resources[id]
-	registry.RUnlock()
-
+	resource, ok := registry.GetResource(id)
 	if !ok {
 		http.Error(w, "Resource not found", http.StatusNotFound)
-		return
+		return nil, false
 	}
+	return resource, true
+}
 
-	fmt.Fprint(w, apis.ResourceInfoToString(resource))
+func GetResource(w http.ResponseWriter, r *http.Request) {
+	if resource, ok := lookupResource(w, r); ok {
+		fmt.Fprint(w, apis.ResourceInfoToString(resource))
+	}
+}
+
+func GetResourceJSON(w http.ResponseWriter, r *http.Request) {
+	if resource, ok := lookupResource(w, r); ok {
+		writeJSON(w, http.StatusOK, resource)
+	}
 }
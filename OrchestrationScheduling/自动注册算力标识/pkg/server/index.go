@@ -0,0 +1,296 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"register-power-resources/pkg/apis"
+)
+
+// 前缀索引按算力标识的前 5 个定长字段分层：城市、行业、企业、资源类型、数据中心
+var indexSegments = []struct {
+	field string
+	width int
+}{
+	{apis.FieldCity, 4},
+	{apis.FieldCompanyType, 2},
+	{apis.FieldCompany, 5},
+	{apis.FieldResourceType, 3},
+	{apis.FieldResourceAZ, 3},
+}
+
+const indexedPrefixLength = 17
+
+// indexNode 为前缀索引的节点，count 为子树中的资源数，用于分页时整体跳过子树。
+// 叶子节点（数据中心一级）保存资源本身，按标识排序的切片在变更后惰性重建。
+// 变更持有注册表写锁；查询只持有读锁，并发查询由 sortMutex 保护重建。
+type indexNode struct {
+	children map[string]*indexNode
+	keys     []string
+	count    int
+
+	resources map[string]*apis.NodeResourceInfo
+	sortMutex sync.Mutex
+	sorted    []*apis.NodeResourceInfo
+}
+
+func newIndexNode(depth int) *indexNode {
+	if depth == len(indexSegments) {
+		return &indexNode{resources: make(map[string]*apis.NodeResourceInfo)}
+	}
+	return &indexNode{children: make(map[string]*indexNode)}
+}
+
+type prefixIndex struct {
+	root *indexNode
+}
+
+func newPrefixIndex() *prefixIndex {
+	return &prefixIndex{root: newIndexNode(0)}
+}
+
+// segmentsOf 按索引层级切分标识，未经校验的过短标识按实际长度截取，不会越界
+func segmentsOf(id string) []string {
+	segments := make([]string, len(indexSegments))
+	offset := 0
+	for i, segment := range indexSegments {
+		segments[i] = id[min(len(id), offset):min(len(id), offset+segment.width)]
+		offset += segment.width
+	}
+	return segments
+}
+
+func (x *prefixIndex) insert(resource *apis.NodeResourceInfo) {
+	node := x.root
+	node.count++
+	for depth, key := range segmentsOf(resource.ID) {
+		child, ok := node.children[key]
+		if !ok {
+			child = newIndexNode(depth + 1)
+			node.children[key] = child
+			i := sort.SearchStrings(node.keys, key)
+			node.keys = append(node.keys, "")
+			copy(node.keys[i+1:], node.keys[i:])
+			node.keys[i] = key
+		}
+		child.count++
+		node = child
+	}
+	node.resources[resource.ID] = resource
+	node.sorted = nil
+}
+
+func (x *prefixIndex) remove(resource *apis.NodeResourceInfo) {
+	segments := segmentsOf(resource.ID)
+	path := []*indexNode{x.root}
+	node := x.root
+	for _, key := range segments {
+		child, ok := node.children[key]
+		if !ok {
+			return
+		}
+		path = append(path, child)
+		node = child
+	}
+	if _, ok := node.resources[resource.ID]; !ok {
+		return
+	}
+	delete(node.resources, resource.ID)
+	node.sorted = nil
+
+	for depth := len(path) - 1; depth >= 0; depth-- {
+		path[depth].count--
+		if depth > 0 && path[depth].count == 0 {
+			parent, key := path[depth-1], segments[depth-1]
+			delete(parent.children, key)
+			i := sort.SearchStrings(parent.keys, key)
+			parent.keys = append(parent.keys[:i], parent.keys[i+1:]...)
+		}
+	}
+}
+
+func (n *indexNode) sortedResources() []*apis.NodeResourceInfo {
+	n.sortMutex.Lock()
+	defer n.sortMutex.Unlock()
+	if n.sorted == nil {
+		n.sorted = make([]*apis.NodeResourceInfo, 0, len(n.resources))
+		for _, resource := range n.resources {
+			n.sorted = append(n.sorted, resource)
+		}
+		sort.Slice(n.sorted, func(i, j int) bool { return n.sorted[i].ID < n.sorted[j].ID })
+	}
+	return n.sorted
+}
+
+// levelMatch 为某一层的匹配条件：exact 精确匹配，prefix 前缀匹配，均为空时匹配全部子节点
+type levelMatch struct {
+	exact  string
+	prefix string
+}
+
+// indexQuery 由选择器拆分而来，levels 在索引上逐层匹配，leaf 为叶子节点内还需逐条校验的条件
+type indexQuery struct {
+	levels []levelMatch
+	leaf   apis.Selector
+	empty  bool
+}
+
+func newIndexQuery(selector apis.Selector) indexQuery {
+	query := indexQuery{levels: make([]levelMatch, len(indexSegments))}
+	leafFields := map[string]string{}
+	for field, value := range selector.Fields {
+		leafFields[field] = value
+	}
+
+	offset := 0
+	for i, segment := range indexSegments {
+		level := &query.levels[i]
+		if value, ok := leafFields[segment.field]; ok {
+			level.exact = value
+			delete(leafFields, segment.field)
+		}
+
+		if len(selector.Prefix) > offset {
+			part := selector.Prefix[offset:min(len(selector.Prefix), offset+segment.width)]
+			switch {
+			case level.exact != "":
+				if !strings.HasPrefix(level.exact, part) {
+					query.empty = true
+				}
+			case len(part) == segment.width:
+				level.exact = part
+			default:
+				level.prefix = part
+			}
+		}
+		offset += segment.width
+	}
+
+	query.leaf = apis.Selector{Fields: leafFields}
+	if len(selector.Prefix) > indexedPrefixLength {
+		query.leaf.Prefix = selector.Prefix
+	}
+	return query
+}
+
+// needsLeafFilter 判断从 depth 层开始的子树是否需要继续筛选
+func (q indexQuery) needsLeafFilter(depth int) bool {
+	for _, level := range q.levels[depth:] {
+		if level.exact != "" || level.prefix != "" {
+			return true
+		}
+	}
+	return !q.leaf.Empty()
+}
+
+// page 收集 [offset, offset+limit) 范围内的匹配结果并统计匹配总数，limit <= 0 表示不限制数量
+type page struct {
+	skip  int
+	limit int
+	out   []*apis.NodeResourceInfo
+	total int
+}
+
+func (p *page) full() bool {
+	return p.limit > 0 && len(p.out) >= p.limit
+}
+
+// addSubtree 将无需再筛选的整棵子树计入结果，已跳过或已满的子树只累加数量
+func (p *page) addSubtree(node *indexNode) {
+	p.total += node.count
+	if p.skip >= node.count {
+		p.skip -= node.count
+		return
+	}
+	if p.full() {
+		return
+	}
+	p.walkAll(node)
+}
+
+func (p *page) walkAll(node *indexNode) {
+	if node.resources != nil {
+		for _, resource := range node.sortedResources() {
+			p.add(resource)
+		}
+		return
+	}
+	for _, key := range node.keys {
+		child := node.children[key]
+		if p.skip >= child.count {
+			p.skip -= child.count
+			continue
+		}
+		if p.full() {
+			return
+		}
+		p.walkAll(child)
+	}
+}
+
+func (p *page) add(resource *apis.NodeResourceInfo) {
+	if p.skip > 0 {
+		p.skip--
+		return
+	}
+	if !p.full() {
+		p.out = append(p.out, resource)
+	}
+}
+
+func (x *prefixIndex) query(selector apis.Selector, offset, limit int) ([]*apis.NodeResourceInfo, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	p := &page{skip: offset, limit: limit, out: []*apis.NodeResourceInfo{}}
+
+	query := newIndexQuery(selector)
+	if !query.empty {
+		x.collect(x.root, 0, query, p)
+	}
+	return p.out, p.total
+}
+
+func (x *prefixIndex) collect(node *indexNode, depth int, query indexQuery, p *page) {
+	if !query.needsLeafFilter(depth) {
+		p.addSubtree(node)
+		return
+	}
+
+	if node.resources != nil {
+		for _, resource := range node.sortedResources() {
+			if query.leaf.Matches(resource) {
+				p.total++
+				p.add(resource)
+			}
+		}
+		return
+	}
+
+	level := query.levels[depth]
+	switch {
+	case level.exact != "":
+		if child, ok := node.children[level.exact]; ok {
+			x.collect(child, depth+1, query, p)
+		}
+	case level.prefix != "":
+		for i := sort.SearchStrings(node.keys, level.prefix); i < len(node.keys); i++ {
+			if !strings.HasPrefix(node.keys[i], level.prefix) {
+				break
+			}
+			x.collect(node.children[node.keys[i]], depth+1, query, p)
+		}
+	default:
+		for _, key := range node.keys {
+			x.collect(node.children[key], depth+1, query, p)
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"register-power-resources/pkg/apis"
+	"register-power-resources/pkg/metrics"
+
+	"golang.org/x/time/rate"
+)
+
+// quotaRetryAfter 为超出配额时建议的重试间隔，与控制器的同步周期一致
+const quotaRetryAfter = 60 * time.Second
+
+// ClientLimits 为一个客户端或企业的限制，零值表示不限制
+type ClientLimits struct {
+	// RequestsPerSecond、Burst 为令牌桶的速率及容量，Burst 为 0 时取不小于速率的整数
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+	// MaxIDs 为最多可注册的算力标识数
+	MaxIDs int `json:"max_ids,omitempty"`
+}
+
+// ClientConfig 为单个客户端的配置，Role、Enterprises 用于租户隔离
+type ClientConfig struct {
+	ClientLimits
+	Role        string   `json:"role,omitempty"`
+	Enterprises []string `json:"enterprises,omitempty"`
+}
+
+// ClientsConfig 为注册接口的限流、配额及租户配置。
+// 客户端按调用方身份（已校验的客户端证书 CN）匹配，未单独配置的客户端使用 Default；
+// 企业按算力标识中的企业编码匹配，只有单独配置的企业受限。
+type ClientsConfig struct {
+	Default     ClientLimits            `json:"default"`
+	Clients     map[string]ClientConfig `json:"clients,omitempty"`
+	Enterprises map[string]ClientLimits `json:"enterprises,omitempty"`
+}
+
+func LoadClientsConfig(path string) (*ClientsConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading clients config: %v", err)
+	}
+
+	config := &ClientsConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error parsing clients config %s: %v", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid clients config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+func (c *ClientsConfig) Validate() error {
+	check := func(name string, limits ClientLimits) error {
+		if limits.RequestsPerSecond < 0 || limits.Burst < 0 || limits.MaxIDs < 0 {
+			return fmt.Errorf("%s: limits must not be negative", name)
+		}
+		return nil
+	}
+
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for client, config := range c.Clients {
+		if err := check("client "+client, config.ClientLimits); err != nil {
+			return err
+		}
+		if config.Role != "" && config.Role != ClientRoleAdmin {
+			return fmt.Errorf("client %s: unknown role %q", client, config.Role)
+		}
+	}
+	for enterprise, limits := range c.Enterprises {
+		if err := check("enterprise "+enterprise, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ClientsConfig) clientLimits(client string) ClientLimits {
+	if config, ok := c.Clients[client]; ok {
+		return config.ClientLimits
+	}
+	return c.Default
+}
+
+// limitError 表示请求因限流或配额被拒绝
+type limitError struct {
+	reason     string
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *limitError) Error() string {
+	return e.message
+}
+
+// limiter 按客户端及企业维护令牌桶，并记录每个客户端注册的算力标识用于配额统计
+type limiter struct {
+	// quotaMutex 串行化配额校验及注册
+	quotaMutex sync.Mutex
+
+	mutex    sync.Mutex
+	config   *ClientsConfig
+	buckets  map[string]*rate.Limiter
+	owners   map[string]string
+	clientID map[string]map[string]bool
+}
+
+var limits *limiter
+
+var rateLimitedRequests = metrics.NewCounterVec("cncos_rate_limited_requests_total",
+	"Total number of registration requests rejected by rate limits or quotas.", "reason")
+
+func init() {
+	metricsRegistry.MustRegister(rateLimitedRequests)
+}
+
+// EnableClientLimits 开启注册接口的限流及配额，未调用时不做限制
+func EnableClientLimits(config *ClientsConfig) {
+	limits = &limiter{
+		config:   config,
+		buckets:  make(map[string]*rate.Limiter),
+		owners:   make(map[string]string),
+		clientID: make(map[string]map[string]bool),
+	}
+}
+
+func (l *limiter) bucket(key string, limits ClientLimits) *rate.Limiter {
+	b, ok := l.buckets[key]
+	if !ok {
+		burst := limits.Burst
+		if burst == 0 {
+			burst = int(math.Ceil(limits.RequestsPerSecond))
+		}
+		b = rate.NewLimiter(rate.Limit(limits.RequestsPerSecond), burst)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// reserve 为每个 key 各取一个令牌，任一 key 无可用令牌时归还已取的令牌并返回需等待的时间
+func (l *limiter) reserve(keys []string, limits []ClientLimits) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	var reservations []*rate.Reservation
+	var delay time.Duration
+	for i, key := range keys {
+		if limits[i].RequestsPerSecond <= 0 {
+			continue
+		}
+		reservation := l.bucket(key, limits[i]).ReserveN(now, 1)
+		reservations = append(reservations, reservation)
+		if d := reservation.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+
+	if delay > 0 {
+		for _, reservation := range reservations {
+			reservation.CancelAt(now)
+		}
+	}
+	return delay
+}
+
+// owned 返回客户端当前仍在注册表中的算力标识数，已被注销、过期或替换的标识在统计时清理
+func (l *limiter) owned(client string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for id := range l.clientID[client] {
+		if _, ok := registry.GetResource(id); !ok {
+			delete(l.clientID[client], id)
+			delete(l.owners, id)
+		}
+	}
+	return len(l.clientID[client])
+}
+
+func (l *limiter) track(client, id string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if owner, ok := l.owners[id]; ok {
+		delete(l.clientID[owner], id)
+	}
+	if l.clientID[client] == nil {
+		l.clientID[client] = make(map[string]bool)
+	}
+	l.clientID[client][id] = true
+	l.owners[id] = client
+}
+
+func (l *limiter) untrack(id string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if owner, ok := l.owners[id]; ok {
+		delete(l.clientID[owner], id)
+		delete(l.owners, id)
+	}
+}
+
+func trackOwner(r *http.Request, resource *apis.NodeResourceInfo, previous *apis.NodeResourceInfo) {
+	if limits == nil || r == nil {
+		return
+	}
+	if previous != nil {
+		limits.untrack(previous.ID)
+	}
+	limits.track(callerIdentity(r), resource.ID)
+}
+
+func untrackOwner(id string) {
+	if limits != nil {
+		limits.untrack(id)
+	}
+}
+
+// checkRateLimit 按调用方身份限流
+func checkRateLimit(r *http.Request) *limitError {
+	if limits == nil {
+		return nil
+	}
+
+	client := callerIdentity(r)
+	delay := limits.reserve([]string{"client/" + client}, []ClientLimits{limits.config.clientLimits(client)})
+	if delay > 0 {
+		return &limitError{reason: "rate", retryAfter: delay,
+			message: fmt.Sprintf("rate limit exceeded for client %s", client)}
+	}
+	return nil
+}
+
+// registerResources 按请求中涉及的企业限流，校验客户端及企业的注册配额后注册 resources。
+// 同一芯片重新上报（容量变化）不占用新的配额；配额校验与注册在 limits.quotaMutex 内完成，并发请求合计不会超出配额
+func registerResources(r *http.Request, resources []*apis.NodeResourceInfo) *limitError {
+	if limits == nil {
+		for _, resource := range resources {
+			addResource(r, resource)
+		}
+		return nil
+	}
+
+	var keys []string
+	var enterpriseLimits []ClientLimits
+	seen := map[string]bool{}
+	for _, resource := range resources {
+		if l, ok := limits.config.Enterprises[resource.Company]; ok && !seen[resource.Company] {
+			keys = append(keys, "enterprise/"+resource.Company)
+			enterpriseLimits = append(enterpriseLimits, l)
+		}
+		seen[resource.Company] = true
+	}
+	if delay := limits.reserve(keys, enterpriseLimits); delay > 0 {
+		return &limitError{reason: "rate", retryAfter: delay,
+			message: "rate limit exceeded for enterprise"}
+	}
+
+	limits.quotaMutex.Lock()
+	defer limits.quotaMutex.Unlock()
+	if err := checkQuota(r, resources); err != nil {
+		return err
+	}
+	for _, resource := range resources {
+		addResource(r, resource)
+	}
+	return nil
+}
+
+// checkQuota 校验客户端及企业的注册配额，调用方需持有 limits.quotaMutex
+func checkQuota(r *http.Request, resources []*apis.NodeResourceInfo) *limitError {
+	config := limits.config
+	added := map[string]int{}
+	total := 0
+	for _, resource := range resources {
+		if _, ok := registry.GetResourceByKey(apis.ResourceKey(resource.ID)); !ok {
+			added[resource.Company]++
+			total++
+		}
+	}
+
+	client := callerIdentity(r)
+	if max := config.clientLimits(client).MaxIDs; max > 0 && total > 0 {
+		if owned := limits.owned(client); owned+total > max {
+			return &limitError{reason: "quota", retryAfter: quotaRetryAfter,
+				message: fmt.Sprintf("quota exceeded for client %s: %d registered, %d new, max %d", client, owned, total, max)}
+		}
+	}
+
+	for enterprise, n := range added {
+		l, ok := config.Enterprises[enterprise]
+		if !ok || l.MaxIDs <= 0 {
+			continue
+		}
+		selector := apis.Selector{Fields: map[string]string{apis.FieldCompany: enterprise}}
+		if _, registered := registry.Select(selector, 0, 1); registered+n > l.MaxIDs {
+			return &limitError{reason: "quota", retryAfter: quotaRetryAfter,
+				message: fmt.Sprintf("quota exceeded for enterprise %s: %d registered, %d new, max %d", enterprise, registered, n, l.MaxIDs)}
+		}
+	}
+	return nil
+}
+
+func writeLimitError(w http.ResponseWriter, err *limitError) {
+	rateLimitedRequests.Inc(err.reason)
+	seconds := int(math.Ceil(err.retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, err.message, http.StatusTooManyRequests)
+}
+
+// RateLimit 为注册接口按调用方身份限流，超出时返回 429 及 Retry-After
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkRateLimit(r); err != nil {
+			writeLimitError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+// registerRequest 以客户端证书 cn（为空时不出示证书）调用注册接口，返回状态码
+func registerRequest(cn string, ids ...string) int {
+	r := httptest.NewRequest(http.MethodPost, "/resources",
+		strings.NewReader(fmt.Sprintf(`{"compute_ids":["%s"]}`, strings.Join(ids, `","`))))
+	if cn != "" {
+		r.TLS = withClientCert(cn)
+	}
+	w := httptest.NewRecorder()
+	RateLimit(http.HandlerFunc(RegisterResource)).ServeHTTP(w, r)
+	return w.Code
+}
+
+func withLimits(t *testing.T, config *ClientsConfig) {
+	saved := registry
+	registry = NewMemoryRegistry()
+	EnableClientLimits(config)
+	t.Cleanup(func() {
+		registry = saved
+		limits = nil
+	})
+}
+
+func TestRateLimitUsesVerifiedIdentity(t *testing.T) {
+	withLimits(t, &ClientsConfig{
+		Default: ClientLimits{RequestsPerSecond: 0.001, Burst: 1},
+		Clients: map[string]ClientConfig{"resource-controller": {ClientLimits: ClientLimits{RequestsPerSecond: 1000, Burst: 100}}},
+	})
+	rnd := rand.New(rand.NewSource(1))
+
+	if code := registerRequest("", testResourceID(rnd, 1)); code != http.StatusCreated {
+		t.Fatalf("first anonymous request got %d", code)
+	}
+	// 请求头不影响身份，仍使用 anonymous 的令牌桶
+	r := httptest.NewRequest(http.MethodPost, "/resources", strings.NewReader(`{"compute_ids":[]}`))
+	r.Header.Set("X-Client-ID", "resource-controller")
+	w := httptest.NewRecorder()
+	RateLimit(http.HandlerFunc(RegisterResource)).ServeHTTP(w, r)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("forged client id got %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	for i := 0; i < 50; i++ {
+		if code := registerRequest("resource-controller", testResourceID(rnd, 100+i)); code != http.StatusCreated {
+			t.Fatalf("request %d of the controller got %d", i, code)
+		}
+	}
+	// 未单独配置的客户端使用 Default，但与 anonymous 分别计数
+	if code := registerRequest("cluster-b", testResourceID(rnd, 2)); code != http.StatusCreated {
+		t.Fatalf("first request of another client got %d", code)
+	}
+}
+
+func TestRegistrationQuotaIsAtomic(t *testing.T) {
+	withLimits(t, &ClientsConfig{
+		Clients: map[string]ClientConfig{"cluster-a": {ClientLimits: ClientLimits{MaxIDs: 10}}},
+	})
+	rnd := rand.New(rand.NewSource(1))
+	ids := make([]string, 40)
+	for i := range ids {
+		ids[i] = testResourceID(rnd, i)
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, len(ids))
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = registerRequest("cluster-a", ids[i])
+		}(i)
+	}
+	wg.Wait()
+
+	created, registered := 0, []string{}
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+			registered = append(registered, ids[i])
+		case http.StatusTooManyRequests:
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	if _, total := registry.Select(apis.Selector{}, 0, 0); created != 10 || total != 10 {
+		t.Fatalf("expected 10 registrations within quota, got %d accepted and %d registered", created, total)
+	}
+
+	// 同一芯片容量变化后的重新上报不占用新的配额
+	changed := []byte(registered[0])
+	changed[32] = '9'
+	if code := registerRequest("cluster-a", string(changed)); code != http.StatusCreated {
+		t.Fatalf("capacity change at quota got %d", code)
+	}
+}
+
+func TestEnterpriseQuota(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	id := testResourceID(rnd, 1)
+	enterprise := apis.ParseResourceInfo(id).Company
+	withLimits(t, &ClientsConfig{Enterprises: map[string]ClientLimits{enterprise: {MaxIDs: 1}}})
+
+	if code := registerRequest("", id); code != http.StatusCreated {
+		t.Fatalf("first registration got %d", code)
+	}
+	other := []byte(testResourceID(rnd, 2))
+	copy(other[6:11], enterprise)
+	if code := registerRequest("", string(other)); code != http.StatusTooManyRequests {
+		t.Fatalf("registration beyond enterprise quota got %d", code)
+	}
+}
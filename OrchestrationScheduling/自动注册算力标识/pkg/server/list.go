@@ -8,16 +8,57 @@ import (
 	"strings"
 )
 
+// listQuery 解析 offset、limit 分页，以及 prefix（算力标识前缀）和 selector（如 city=1101,enterprise=20001）
+func listQuery(r *http.Request) (selector apis.Selector, offset, limit int, err error) {
+	offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+
+	selector, err = apis.ParseSelector(r.URL.Query().Get("selector"))
+	if err != nil {
+		return
+	}
+	selector.Prefix = r.URL.Query().Get("prefix")
+	return
+}
+
+// ListResources 支持 offset、limit 分页，以及 prefix（算力标识前缀）和
+// selector（如 city=1101,enterprise=20001）筛选，匹配总数通过 X-Total-Count 返回
 func ListResources(w http.ResponseWriter, r *http.Request) {
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	selector, offset, limit, err := listQuery(r)
+	if err != nil {
+		requestErrors.Inc("list")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	resources := registry.GetResources(offset, limit)
+	resources, total := requestScope(r).selectResources(selector, offset, limit)
 
 	var resourceStrings []string
 	for _, resource := range resources {
 		resourceStrings = append(resourceStrings, apis.ResourceInfoToString(resource))
 	}
 
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	fmt.Fprint(w, strings.Join(resourceStrings, "\n"))
 }
+
+// ListResourcesJSON 与 ListResources 的查询参数相同，以 apis.ResourceList 返回解析后的资源
+func ListResourcesJSON(w http.ResponseWriter, r *http.Request) {
+	selector, offset, limit, err := listQuery(r)
+	if err != nil {
+		requestErrors.Inc("list")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 先取序号再查询，之后的变更都能通过 watch 获取
+	revision := watchEvents.currentRevision()
+	resources, total := requestScope(r).selectResources(selector, offset, limit)
+	writeJSON(w, http.StatusOK, apis.ResourceList{
+		Items:    resources,
+		Total:    total,
+		Offset:   offset,
+		Limit:    limit,
+		Revision: revision,
+	})
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"register-power-resources/pkg/apis"
+)
+
+const defaultMatchLimit = 20
+
+// 各评分项的满分，总分 100
+const (
+	scoreCompute    = 40.0
+	scoreEfficiency = 30.0
+	scoreNetwork    = 20.0
+	scoreChips      = 10.0
+)
+
+// scoreHistory 为任务路径历史带来的最大加（减）分，仅在按任务模板匹配时计入
+const scoreHistory = 10.0
+
+var networkScores = map[string]float64{
+	apis.NetworkTypeIB:       1.0,
+	apis.NetworkTypeRoCE:     0.75,
+	apis.NetworkTypeEthernet: 0.5,
+	apis.NetworkTypeOther:    0.25,
+}
+
+// matchGroup 汇总同一服务器上同一型号的芯片
+type matchGroup struct {
+	candidate apis.MatchCandidate
+	pflops    float64
+}
+
+// MatchResources 按任务需求筛选已注册资源，并按得分从高到低返回
+func MatchResources(req apis.MatchRequest) (apis.MatchResponse, error) {
+	return matchResources(req, nil, allTenants)
+}
+
+// matchKey 去掉芯片唯一编号后，同一服务器上同一型号的芯片归为一组
+func matchKey(resource *apis.NodeResourceInfo) string {
+	return strings.TrimSuffix(apis.ResourceKey(resource.ID), resource.ChipUniqNumber)
+}
+
+// parseMatchRequest 校验匹配条件，返回合并了芯片类型、型号的选择器及网络类型编码
+func parseMatchRequest(req apis.MatchRequest) (apis.Selector, string, error) {
+	selector, err := apis.ParseSelector(req.Selector)
+	if err != nil {
+		return apis.Selector{}, "", err
+	}
+
+	// 芯片类型、型号作为选择器字段，交由注册表索引筛选
+	if req.ChipType != "" {
+		chipType, err := apis.ParseChipType(req.ChipType)
+		if err != nil {
+			return apis.Selector{}, "", err
+		}
+		selector.Fields[apis.FieldChipType] = chipType
+	}
+	if req.ChipModel != "" {
+		selector.Fields[apis.FieldChipModel] = req.ChipModel
+	}
+
+	networkType := ""
+	if req.NetworkType != "" {
+		if networkType, err = apis.ParseNetworkType(req.NetworkType); err != nil {
+			return apis.Selector{}, "", err
+		}
+	}
+	return selector, networkType, nil
+}
+
+func matchResources(req apis.MatchRequest, history map[string]*pathHistory, scope tenantScope) (apis.MatchResponse, error) {
+	selector, networkType, err := parseMatchRequest(req)
+	if err != nil {
+		return apis.MatchResponse{}, err
+	}
+
+	response := apis.MatchResponse{Candidates: []apis.MatchCandidate{}}
+
+	resources, _ := scope.selectResources(selector, 0, 0)
+	groups := map[string]*matchGroup{}
+	var order []string
+	for _, resource := range resources {
+		if networkType != "" && resource.NetworkType != networkType {
+			continue
+		}
+		if req.MaxPowerWatts > 0 && resource.PowerWatts() > req.MaxPowerWatts {
+			continue
+		}
+		pflops := float64(resource.ComputePFLOPs())
+		if pflops < req.MinPFLOPs {
+			continue
+		}
+
+		key := matchKey(resource)
+		group, ok := groups[key]
+		if !ok {
+			group = &matchGroup{
+				candidate: apis.MatchCandidate{
+					City:          resource.City,
+					Company:       resource.Company,
+					ResourceAZ:    resource.ResourceAZ,
+					ChipType:      resource.ChipType,
+					ChipModel:     resource.ChipModel,
+					ComputePFLOPs: pflops,
+					PowerWatts:    resource.PowerWatts(),
+					NetworkType:   resource.NetworkType,
+				},
+				pflops: pflops,
+			}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.candidate.ComputeIDs = append(group.candidate.ComputeIDs, resource.ID)
+		group.candidate.Chips++
+	}
+
+	var maxPFLOPs, maxEfficiency float64
+	var maxChips int
+	for _, group := range groups {
+		maxPFLOPs = maxFloat(maxPFLOPs, group.pflops)
+		maxEfficiency = maxFloat(maxEfficiency, efficiency(group))
+		if group.candidate.Chips > maxChips {
+			maxChips = group.candidate.Chips
+		}
+	}
+
+	candidates := make([]apis.MatchCandidate, 0, len(groups))
+	for _, key := range order {
+		group := groups[key]
+		scoreGroup(group, req.MinPFLOPs, networkType, maxPFLOPs, maxEfficiency, maxChips)
+		if h, ok := history[key]; ok {
+			scoreHistoryOf(group, h)
+		}
+		candidates = append(candidates, group.candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultMatchLimit
+	}
+	response.Total = len(candidates)
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	response.Candidates = candidates
+	return response, nil
+}
+
+func scoreGroup(group *matchGroup, minPFLOPs float64, networkType string, maxPFLOPs, maxEfficiency float64, maxChips int) {
+	c := &group.candidate
+	var explanation []string
+
+	// 计算量：有最低要求时按满足倍数计分（2 倍封顶），否则按与最大候选的比例计分
+	var compute float64
+	if minPFLOPs > 0 {
+		compute = scoreCompute * minFloat(group.pflops/minPFLOPs, 2) / 2
+		explanation = append(explanation, fmt.Sprintf("compute %.3f PFLOPs vs required %.3f: +%.1f",
+			group.pflops, minPFLOPs, compute))
+	} else if maxPFLOPs > 0 {
+		compute = scoreCompute * group.pflops / maxPFLOPs
+		explanation = append(explanation, fmt.Sprintf("compute %.3f PFLOPs vs best %.3f: +%.1f",
+			group.pflops, maxPFLOPs, compute))
+	}
+
+	// 能效：每千瓦提供的计算量，与最优候选相比
+	var eff float64
+	if maxEfficiency > 0 {
+		eff = scoreEfficiency * efficiency(group) / maxEfficiency
+		explanation = append(explanation, fmt.Sprintf("efficiency %.3f PFLOPs/kW vs best %.3f: +%.1f",
+			efficiency(group), maxEfficiency, eff))
+	} else {
+		explanation = append(explanation, "efficiency unknown: power not reported: +0.0")
+	}
+
+	// 网络：指定网络类型时已作为硬性条件，满分；否则按网络类型计分
+	var network float64
+	networkName := apis.CodeName(apis.NetworkTypeNames, c.NetworkType)
+	if networkType != "" {
+		network = scoreNetwork
+		explanation = append(explanation, fmt.Sprintf("network %s matches requirement: +%.1f", networkName, network))
+	} else {
+		network = scoreNetwork * networkScores[c.NetworkType]
+		explanation = append(explanation, fmt.Sprintf("network %s: +%.1f", networkName, network))
+	}
+
+	// 芯片数量：同型号芯片越多，可调度余量越大
+	chips := scoreChips * float64(c.Chips) / float64(maxChips)
+	explanation = append(explanation, fmt.Sprintf("%d chips vs most %d: +%.1f", c.Chips, maxChips, chips))
+
+	c.Score = roundScore(compute + eff + network + chips)
+	c.Explanation = explanation
+}
+
+// scoreHistoryOf 按该组芯片在同一任务模板上的历史执行结果加减分
+func scoreHistoryOf(group *matchGroup, h *pathHistory) {
+	runs := h.succeeded + h.failed
+	if runs == 0 {
+		return
+	}
+	adjust := scoreHistory * float64(h.succeeded-h.failed) / float64(runs)
+	group.candidate.Score = roundScore(group.candidate.Score + adjust)
+	group.candidate.Explanation = append(group.candidate.Explanation,
+		fmt.Sprintf("history %d succeeded, %d failed on this template (avg %s): %+.1f",
+			h.succeeded, h.failed, h.averageDuration(), adjust))
+}
+
+func efficiency(group *matchGroup) float64 {
+	if group.candidate.PowerWatts <= 0 {
+		return 0
+	}
+	return group.pflops / (float64(group.candidate.PowerWatts) / 1000)
+}
+
+func roundScore(score float64) float64 {
+	return float64(int64(score*100+0.5)) / 100
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func MatchResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	var req apis.MatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		requestErrors.Inc("match")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := matchResources(req, nil, requestScope(r))
+	if err != nil {
+		requestErrors.Inc("match")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+// matchResourceID 生成北京 20001 企业某台服务器上的 GPU 算力标识
+func matchResourceID(server, pflops, watts int, networkType, uniq string) string {
+	return "1101tc20001401501" + "02602001601004" +
+		fmt.Sprintf("F%04dS%07dN%06dP%05d", pflops, 1000, 10000, watts) +
+		networkType + "00" + fmt.Sprintf("%032b", server) +
+		apis.ChipTypeGPU + "00000001" + uniq
+}
+
+func postMatch(t *testing.T, body string) (int, apis.MatchResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	MatchResourcesHandler(w, httptest.NewRequest(http.MethodPost, "/v1/match", strings.NewReader(body)))
+	var response apis.MatchResponse
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, response
+}
+
+func TestMatchResources(t *testing.T) {
+	withLimits(t, nil)
+	for _, id := range []string{
+		// 服务器 1：两张芯片，8 PFLOPs，2000 W，IB
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001"),
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00002"),
+		// 服务器 2：一张芯片，4 PFLOPs，2000 W，以太网
+		matchResourceID(2, 4, 2000, apis.NetworkTypeEthernet, "00001"),
+	} {
+		registry.AddResource(apis.ParseResourceInfo(id))
+	}
+
+	code, response := postMatch(t, `{"chip_type":"gpu"}`)
+	if code != http.StatusOK || response.Total != 2 || len(response.Candidates) != 2 {
+		t.Fatalf("unexpected response %d %+v", code, response)
+	}
+	best, second := response.Candidates[0], response.Candidates[1]
+	// 计算量 40 + 能效 30 + IB 20 + 芯片数量 10
+	if best.Chips != 2 || len(best.ComputeIDs) != 2 || best.ComputePFLOPs != 8 || best.Score != 100 {
+		t.Fatalf("unexpected best candidate %+v", best)
+	}
+	// 计算量 20 + 能效 15 + 以太网 10 + 芯片数量 5
+	if second.Chips != 1 || second.Score != 50 || len(second.Explanation) != 4 {
+		t.Fatalf("unexpected second candidate %+v", second)
+	}
+
+	for body, want := range map[string]int{
+		`{"min_pflops":5}`:            1,
+		`{"network_type":"ethernet"}`: 1,
+		`{"max_power_watts":1000}`:    0,
+		`{"chip_type":"npu"}`:         0,
+		`{"limit":1}`:                 2,
+	} {
+		code, response := postMatch(t, body)
+		if code != http.StatusOK || response.Total != want {
+			t.Errorf("%s: got %d with %d candidates, want %d", body, code, response.Total, want)
+		}
+	}
+
+	// 有最低计算量要求时按满足倍数计分，2 倍封顶
+	if _, response := postMatch(t, `{"min_pflops":2}`); response.Candidates[1].Score != 70 {
+		t.Fatalf("unexpected score with min_pflops %+v", response.Candidates[1])
+	}
+}
+
+func TestMatchResourcesRejectsUnsupportedRequirements(t *testing.T) {
+	withLimits(t, nil)
+	for _, body := range []string{
+		`{"chip_type":"tpu"}`,
+		`{"network_type":"wifi"}`,
+		`{"selector":"unknown=1"}`,
+		`{`,
+	} {
+		if code, _ := postMatch(t, body); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", body, code)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+
+	"register-power-resources/pkg/apis"
+	"register-power-resources/pkg/metrics"
+)
+
+var capacityLabels = []string{"city", "enterprise", "resource_type", "chip_type", "chip_model"}
+
+var (
+	metricsRegistry = metrics.NewRegistry()
+
+	registerRequests = metrics.NewCounterVec("cncos_register_requests_total",
+		"Total number of register requests received.")
+	unregisterRequests = metrics.NewCounterVec("cncos_unregister_requests_total",
+		"Total number of unregister requests received.")
+	requestErrors = metrics.NewCounterVec("cncos_registry_errors_total",
+		"Total number of failed registry requests.", "operation")
+
+	registeredChips = metrics.NewGaugeVec("cncos_registered_chips",
+		"Number of registered chip-level compute ids.", capacityLabels...)
+	registeredPFLOPs = metrics.NewGaugeVec("cncos_registered_compute_pflops",
+		"Registered compute capacity in PFLOPs, counted once per server address.", capacityLabels...)
+	registeredStorageGB = metrics.NewGaugeVec("cncos_registered_storage_gb",
+		"Registered storage capacity in GB, counted once per data center.", capacityLabels...)
+	registeredBandwidthMbps = metrics.NewGaugeVec("cncos_registered_bandwidth_mbps",
+		"Registered network bandwidth in Mbps, counted once per data center.", capacityLabels...)
+	registeredPowerWatts = metrics.NewGaugeVec("cncos_registered_power_watts",
+		"Registered power consumption in watts, counted once per server address.", capacityLabels...)
+)
+
+func init() {
+	metricsRegistry.MustRegister(registerRequests, unregisterRequests, requestErrors,
+		registeredChips, registeredPFLOPs, registeredStorageGB, registeredBandwidthMbps, registeredPowerWatts)
+	metricsRegistry.OnCollect(updateCapacityMetrics)
+
+	registerRequests.Add(0)
+	unregisterRequests.Add(0)
+}
+
+// Metrics 输出 Prometheus 指标。容量指标按企业分组，开启租户隔离时仅 admin 客户端可采集
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	if !requestScope(r).all {
+		requestErrors.Inc("tenant")
+		http.Error(w, "metrics are only available to admin clients", http.StatusForbidden)
+		return
+	}
+	metricsRegistry.ServeHTTP(w, r)
+}
+
+// updateCapacityMetrics 在每次采集时根据注册表重新计算容量指标。
+// 算力标识中的计算量、功耗是芯片所在服务器的总量，存储、带宽是数据中心的总量（见 CPID/算力标识体系更新版.md 2.7），
+// 因此同一标签组内分别按算力互联网地址、数据中心去重后再累加。
+func updateCapacityMetrics() {
+	resources := registry.GetResources(0, 0)
+
+	for _, gauge := range []*metrics.GaugeVec{registeredChips, registeredPFLOPs,
+		registeredStorageGB, registeredBandwidthMbps, registeredPowerWatts} {
+		gauge.Reset()
+	}
+
+	servers := make(map[[6]string]bool)
+	dataCenters := make(map[[6]string]bool)
+	for _, resource := range resources {
+		labels := capacityLabelValues(resource)
+		registeredChips.Add(1, labels...)
+
+		server := [6]string{labels[0], labels[1], labels[2], labels[3], labels[4], serverKey(resource)}
+		if !servers[server] {
+			servers[server] = true
+			registeredPFLOPs.Add(float64(resource.ComputePFLOPs()), labels...)
+			registeredPowerWatts.Add(float64(resource.PowerWatts()), labels...)
+		}
+
+		dataCenter := [6]string{labels[0], labels[1], labels[2], labels[3], labels[4], dataCenterKey(resource)}
+		if !dataCenters[dataCenter] {
+			dataCenters[dataCenter] = true
+			registeredStorageGB.Add(float64(resource.StorageGB()), labels...)
+			registeredBandwidthMbps.Add(float64(resource.BandwidthMbps()), labels...)
+		}
+	}
+}
+
+// serverKey 为资源所在服务器：数据中心及算力互联网地址。按节点或集群聚合上报的标识同样以地址区分服务器（或合并的一组服务器）
+func serverKey(resource *apis.NodeResourceInfo) string {
+	return dataCenterKey(resource) + resource.PowerResourceAddress
+}
+
+// dataCenterKey 为资源所在数据中心：城市、行业、企业、资源类型、数据中心编码
+func dataCenterKey(resource *apis.NodeResourceInfo) string {
+	return resource.City + resource.CompanyType + resource.Company + resource.ResourceType + resource.ResourceAZ
+}
+
+func capacityLabelValues(resource *apis.NodeResourceInfo) []string {
+	return []string{resource.City, resource.Company, resource.ResourceType, resource.ChipType, resource.ChipModel}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+func TestCapacityMetrics(t *testing.T) {
+	withLimits(t, nil)
+	for _, id := range []string{
+		// 同一服务器上的两张芯片，服务器容量只计一次
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001"),
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00002"),
+		matchResourceID(2, 4, 1000, apis.NetworkTypeIB, "00001"),
+	} {
+		registry.AddResource(apis.ParseResourceInfo(id))
+	}
+
+	w := httptest.NewRecorder()
+	Metrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := w.Body.String()
+
+	labels := `{city="1101",enterprise="20001",resource_type="401",chip_type="00000",chip_model="00000001"}`
+	for _, line := range []string{
+		"cncos_registered_chips" + labels + " 3",
+		"cncos_registered_compute_pflops" + labels + " 12",
+		// 两台服务器在同一数据中心，存储、带宽只计一次
+		"cncos_registered_storage_gb" + labels + " 1000",
+		"cncos_registered_bandwidth_mbps" + labels + " 10000",
+		"cncos_registered_power_watts" + labels + " 3000",
+		"# TYPE cncos_register_requests_total counter",
+		"# TYPE cncos_registry_errors_total counter",
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, output)
+		}
+	}
+
+	// 并发采集不会交错重置与累加
+	var wg sync.WaitGroup
+	outputs := make([]string, 8)
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			Metrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			outputs[i] = w.Body.String()
+		}(i)
+	}
+	wg.Wait()
+	for _, concurrent := range outputs {
+		if !strings.Contains(concurrent, "cncos_registered_chips"+labels+" 3\n") ||
+			!strings.Contains(concurrent, "cncos_registered_compute_pflops"+labels+" 12\n") {
+			t.Fatalf("inconsistent output from concurrent scrapes:\n%s", concurrent)
+		}
+	}
+
+	// 注册表清空后不再输出旧的标签组
+	registry.Replace(nil)
+	w = httptest.NewRecorder()
+	Metrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(w.Body.String(), "cncos_registered_chips{") {
+		t.Fatalf("stale capacity series after the registry was emptied:\n%s", w.Body)
+	}
+}
+
+func TestMetricsRequireAdminWithTenancy(t *testing.T) {
+	EnableTenancy(&ClientsConfig{Clients: map[string]ClientConfig{
+		"ops":       {Role: ClientRoleAdmin},
+		"cluster-a": {Enterprises: []string{"20001"}},
+	}})
+	defer EnableTenancy(nil)
+
+	for cn, want := range map[string]int{"ops": http.StatusOK, "cluster-a": http.StatusForbidden, "": http.StatusForbidden} {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if cn != "" {
+			r.TLS = withClientCert(cn)
+		}
+		w := httptest.NewRecorder()
+		Metrics(w, r)
+		if w.Code != want {
+			t.Errorf("%q: got %d, want %d", cn, w.Code, want)
+		}
+	}
+}
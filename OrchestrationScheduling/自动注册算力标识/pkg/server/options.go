@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options 为资源展示服务的监听配置，默认值可通过环境变量设置，命令行参数优先
+type Options struct {
+	ListenAddr string
+
+	TLSCertFile       string
+	TLSKeyFile        string
+	ClientCAFile      string
+	RequireClientCert bool
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	AuditLogFile       string
+	AuditLogMaxSizeMB  int
+	AuditLogMaxBackups int
+	ResourceTTL        time.Duration
+
+	ClientsConfigFile string
+	TenantIsolation   bool
+
+	// envErrors 为无法解析的环境变量，由 Validate 返回
+	envErrors []string
+}
+
+func NewOptions() *Options {
+	o := &Options{}
+	o.ListenAddr = envString("SERVER_LISTEN_ADDR", ":8080")
+	o.TLSCertFile = envString("SERVER_TLS_CERT_FILE", "")
+	o.TLSKeyFile = envString("SERVER_TLS_KEY_FILE", "")
+	o.ClientCAFile = envString("SERVER_TLS_CLIENT_CA_FILE", "")
+	o.RequireClientCert = o.envBool("SERVER_TLS_REQUIRE_CLIENT_CERT", false)
+	o.ReadTimeout = o.envDuration("SERVER_READ_TIMEOUT", 30*time.Second)
+	o.WriteTimeout = o.envDuration("SERVER_WRITE_TIMEOUT", 60*time.Second)
+	o.IdleTimeout = o.envDuration("SERVER_IDLE_TIMEOUT", 120*time.Second)
+	o.ShutdownTimeout = o.envDuration("SERVER_SHUTDOWN_TIMEOUT", 25*time.Second)
+	o.AuditLogFile = envString("SERVER_AUDIT_LOG_FILE", "")
+	o.AuditLogMaxSizeMB = o.envInt("SERVER_AUDIT_LOG_MAX_SIZE_MB", 100)
+	o.AuditLogMaxBackups = o.envInt("SERVER_AUDIT_LOG_MAX_BACKUPS", 5)
+	o.ResourceTTL = o.envDuration("SERVER_RESOURCE_TTL", 0)
+	o.ClientsConfigFile = envString("SERVER_CLIENTS_CONFIG", "")
+	o.TenantIsolation = o.envBool("SERVER_TENANT_ISOLATION", false)
+	return o
+}
+
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ListenAddr, "listen-addr", o.ListenAddr, "Address to listen on (env SERVER_LISTEN_ADDR)")
+	fs.StringVar(&o.TLSCertFile, "tls-cert-file", o.TLSCertFile,
+		"Server certificate file, enables HTTPS together with -tls-key-file (env SERVER_TLS_CERT_FILE)")
+	fs.StringVar(&o.TLSKeyFile, "tls-key-file", o.TLSKeyFile, "Server private key file (env SERVER_TLS_KEY_FILE)")
+	fs.StringVar(&o.ClientCAFile, "tls-client-ca-file", o.ClientCAFile,
+		"CA bundle used to verify client certificates (env SERVER_TLS_CLIENT_CA_FILE)")
+	fs.BoolVar(&o.RequireClientCert, "tls-require-client-cert", o.RequireClientCert,
+		"Reject clients without a certificate signed by -tls-client-ca-file (env SERVER_TLS_REQUIRE_CLIENT_CERT)")
+	fs.DurationVar(&o.ReadTimeout, "read-timeout", o.ReadTimeout, "Maximum duration for reading a request (env SERVER_READ_TIMEOUT)")
+	fs.DurationVar(&o.WriteTimeout, "write-timeout", o.WriteTimeout, "Maximum duration for writing a response (env SERVER_WRITE_TIMEOUT)")
+	fs.DurationVar(&o.IdleTimeout, "idle-timeout", o.IdleTimeout, "Keep-alive idle timeout (env SERVER_IDLE_TIMEOUT)")
+	fs.DurationVar(&o.ShutdownTimeout, "shutdown-timeout", o.ShutdownTimeout,
+		"Time allowed for in-flight requests to drain on shutdown (env SERVER_SHUTDOWN_TIMEOUT)")
+	fs.StringVar(&o.AuditLogFile, "audit-log-file", o.AuditLogFile,
+		"Append-only audit log of registry mutations, disabled when empty (env SERVER_AUDIT_LOG_FILE)")
+	fs.IntVar(&o.AuditLogMaxSizeMB, "audit-log-max-size-mb", o.AuditLogMaxSizeMB,
+		"Rotate the audit log after it reaches this size (env SERVER_AUDIT_LOG_MAX_SIZE_MB)")
+	fs.IntVar(&o.AuditLogMaxBackups, "audit-log-max-backups", o.AuditLogMaxBackups,
+		"Number of rotated audit log files to keep (env SERVER_AUDIT_LOG_MAX_BACKUPS)")
+	fs.DurationVar(&o.ResourceTTL, "resource-ttl", o.ResourceTTL,
+		"Expire resources not re-registered within this duration, 0 disables expiry (env SERVER_RESOURCE_TTL)")
+	fs.StringVar(&o.ClientsConfigFile, "clients-config", o.ClientsConfigFile,
+		"JSON file with per-client and per-enterprise rate limits and quotas, disabled when empty (env SERVER_CLIENTS_CONFIG)")
+	fs.BoolVar(&o.TenantIsolation, "tenant-isolation", o.TenantIsolation,
+		"Restrict clients, identified by their verified certificate CN, to the enterprises listed in -clients-config; requires -tls-require-client-cert (env SERVER_TENANT_ISOLATION)")
+}
+
+func (o *Options) Validate() error {
+	if len(o.envErrors) > 0 {
+		return fmt.Errorf("invalid environment: %s", strings.Join(o.envErrors, "; "))
+	}
+	if o.ListenAddr == "" {
+		return errors.New("listen address must not be empty")
+	}
+	if (o.TLSCertFile == "") != (o.TLSKeyFile == "") {
+		return errors.New("-tls-cert-file and -tls-key-file must be set together")
+	}
+	if o.ClientCAFile != "" && o.TLSCertFile == "" {
+		return errors.New("-tls-client-ca-file requires -tls-cert-file and -tls-key-file")
+	}
+	if o.RequireClientCert && o.ClientCAFile == "" {
+		return errors.New("-tls-require-client-cert requires -tls-client-ca-file")
+	}
+	if o.TenantIsolation && o.ClientsConfigFile == "" {
+		return errors.New("-tenant-isolation requires -clients-config")
+	}
+	// 租户按客户端证书 CN 划分，必须要求并校验客户端证书
+	if o.TenantIsolation && !o.RequireClientCert {
+		return errors.New("-tenant-isolation requires -tls-require-client-cert and -tls-client-ca-file")
+	}
+	if o.AuditLogMaxSizeMB < 0 || o.AuditLogMaxBackups < 0 {
+		return errors.New("audit log size and backups must not be negative")
+	}
+	return nil
+}
+
+func (o *Options) TLSEnabled() bool {
+	return o.TLSCertFile != ""
+}
+
+// TLSConfig 构造服务端 TLS 配置，配置了客户端 CA 时校验客户端证书
+func (o *Options) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if o.ClientCAFile == "" {
+		return config, nil
+	}
+
+	caData, err := ioutil.ReadFile(o.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA file: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", o.ClientCAFile)
+	}
+
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if o.RequireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Run 启动 HTTP(S) 服务，ctx 结束后停止接收新连接并等待处理中的请求完成
+func Run(ctx context.Context, o *Options, handler http.Handler) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:         o.ListenAddr,
+		Handler:      handler,
+		ReadTimeout:  o.ReadTimeout,
+		WriteTimeout: o.WriteTimeout,
+		IdleTimeout:  o.IdleTimeout,
+	}
+	if o.TLSEnabled() {
+		tlsConfig, err := o.TLSConfig()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if o.TLSEnabled() {
+			fmt.Printf("Starting server at %s (https)\n", o.ListenAddr)
+			err = srv.ListenAndServeTLS(o.TLSCertFile, o.TLSKeyFile)
+		} else {
+			fmt.Printf("Starting server at %s\n", o.ListenAddr)
+			err = srv.ListenAndServe()
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Printf("Shutting down server, draining in-flight requests for up to %s\n", o.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), o.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down server: %v", err)
+	}
+	if err := <-errCh; err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func envString(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// envBool、envInt、envDuration 在环境变量未设置或为空时返回默认值，无法解析时记录错误，启动时由 Validate 报出
+func (o *Options) envBool(key string, defaultValue bool) bool {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		o.envErrors = append(o.envErrors, fmt.Sprintf("%s=%q is not a boolean", key, s))
+		return defaultValue
+	}
+	return value
+}
+
+func (o *Options) envInt(key string, defaultValue int) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		o.envErrors = append(o.envErrors, fmt.Sprintf("%s=%q is not an integer", key, s))
+		return defaultValue
+	}
+	return value
+}
+
+func (o *Options) envDuration(key string, defaultValue time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(s)
+	if err != nil {
+		o.envErrors = append(o.envErrors, fmt.Sprintf("%s=%q is not a duration", key, s))
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsFromEnvironment(t *testing.T) {
+	t.Setenv("SERVER_READ_TIMEOUT", "5s")
+	t.Setenv("SERVER_AUDIT_LOG_MAX_BACKUPS", "2")
+	t.Setenv("SERVER_TENANT_ISOLATION", "")
+	o := NewOptions()
+	if o.ReadTimeout != 5*time.Second || o.AuditLogMaxBackups != 2 || o.TenantIsolation || o.WriteTimeout != 60*time.Second {
+		t.Fatalf("unexpected options %+v", o)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 无法解析的值在启动时报错，而不是静默使用默认值
+	t.Setenv("SERVER_READ_TIMEOUT", "30")
+	t.Setenv("SERVER_TLS_REQUIRE_CLIENT_CERT", "yes")
+	t.Setenv("SERVER_AUDIT_LOG_MAX_SIZE_MB", "1G")
+	err := NewOptions().Validate()
+	if err == nil {
+		t.Fatal("invalid environment accepted")
+	}
+	for _, key := range []string{"SERVER_READ_TIMEOUT", "SERVER_TLS_REQUIRE_CLIENT_CERT", "SERVER_AUDIT_LOG_MAX_SIZE_MB"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not mention %s", err, key)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for name, modify := range map[string]func(o *Options){
+		"empty listen address":   func(o *Options) { o.ListenAddr = "" },
+		"cert without key":       func(o *Options) { o.TLSCertFile = "tls.crt" },
+		"client CA without TLS":  func(o *Options) { o.ClientCAFile = "ca.crt" },
+		"client cert without CA": func(o *Options) { o.TLSCertFile, o.TLSKeyFile, o.RequireClientCert = "tls.crt", "tls.key", true },
+		"negative audit backups": func(o *Options) { o.AuditLogMaxBackups = -1 },
+	} {
+		o := NewOptions()
+		modify(o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func writeTestCA(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOptionsTLSConfig(t *testing.T) {
+	o := NewOptions()
+	config, err := o.TLSConfig()
+	if err != nil || config.MinVersion != tls.VersionTLS12 || config.ClientCAs != nil {
+		t.Fatalf("unexpected config without client CA: %+v, %v", config, err)
+	}
+
+	o.ClientCAFile = writeTestCA(t)
+	if config, err = o.TLSConfig(); err != nil || config.ClientAuth != tls.VerifyClientCertIfGiven || config.ClientCAs == nil {
+		t.Fatalf("unexpected config with client CA: %+v, %v", config, err)
+	}
+	o.RequireClientCert = true
+	if config, err = o.TLSConfig(); err != nil || config.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("client certificate not required: %+v, %v", config, err)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.crt")
+	ioutil.WriteFile(invalid, []byte("not a certificate"), 0600)
+	for _, path := range []string{invalid, filepath.Join(t.TempDir(), "missing.crt")} {
+		o.ClientCAFile = path
+		if _, err := o.TLSConfig(); err == nil {
+			t.Errorf("%s: accepted", path)
+		}
+	}
+}
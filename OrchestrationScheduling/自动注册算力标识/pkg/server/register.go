@@ -12,27 +12,80 @@ type RequestBody struct {
 	ComputeIDs []string `json:"compute_ids"`
 }
 
+type UnregisterResponse struct {
+	Removed int `json:"removed"`
+}
+
 func RegisterResource(w http.ResponseWriter, r *http.Request) {
+	registerRequests.Inc()
 
 	decoder := json.NewDecoder(r.Body)
 	var body RequestBody
 	err := decoder.Decode(&body)
 	if err != nil {
+		requestErrors.Inc("register")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	resources := make([]*apis.NodeResourceInfo, 0, len(body.ComputeIDs))
 	for _, computeID := range body.ComputeIDs {
-		resource := apis.ParseResourceInfo(computeID)
-		registry.AddResource(resource)
-		w.WriteHeader(http.StatusCreated)
-		fmt.Println(computeID)
+		if err := apis.ValidateResourceID(computeID); err != nil {
+			requestErrors.Inc("register")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resources = append(resources, apis.ParseResourceInfo(computeID))
+	}
+
+	if id, ok := requestScope(r).forbidden(body.ComputeIDs); ok {
+		writeForbidden(w, id)
+		return
+	}
+
+	if err := registerResources(r, resources); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	for _, resource := range resources {
+		fmt.Println(resource.ID)
 	}
+	w.WriteHeader(http.StatusCreated)
 }
 
 func UnregisterResource(w http.ResponseWriter, r *http.Request) {
+	unregisterRequests.Inc()
+
 	id := mux.Vars(r)["id"]
-	registry.DeleteResource(id)
+	if !requestScope(r).allows(id) {
+		writeForbidden(w, id)
+		return
+	}
+	deleteResource(r, id)
 	w.WriteHeader(http.StatusNoContent)
 	//fmt.Println(registry.Resources)
 }
+
+// UnregisterResources 批量注销请求体中的算力标识，未注册的标识忽略，返回实际移除的数量
+func UnregisterResources(w http.ResponseWriter, r *http.Request) {
+	unregisterRequests.Inc()
+
+	var body RequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		requestErrors.Inc("unregister")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id, ok := requestScope(r).forbidden(body.ComputeIDs); ok {
+		writeForbidden(w, id)
+		return
+	}
+
+	removed := 0
+	for _, id := range body.ComputeIDs {
+		if deleteResource(r, id) {
+			removed++
+		}
+	}
+	writeJSON(w, http.StatusOK, UnregisterResponse{Removed: removed})
+}
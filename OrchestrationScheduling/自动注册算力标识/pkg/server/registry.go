@@ -3,36 +3,179 @@ package server
 import (
 	"register-power-resources/pkg/apis"
 	"sync"
+	"time"
 )
 
-type Registry struct {
+// Registry 为算力标识注册表的存储抽象，HTTP 接口、快照导入导出均基于该接口实现。
+// 注册表以 apis.ResourceKey 区分芯片，同一芯片重新上报新的容量时替换原有标识。
+type Registry interface {
+	// AddResource 注册或刷新资源，返回被替换的同一芯片的原有资源
+	AddResource(resource *apis.NodeResourceInfo) (previous *apis.NodeResourceInfo)
+	DeleteResource(id string) (*apis.NodeResourceInfo, bool)
+	GetResource(id string) (*apis.NodeResourceInfo, bool)
+	// GetResourceByKey 按 apis.ResourceKey 查找同一芯片当前注册的资源
+	GetResourceByKey(key string) (*apis.NodeResourceInfo, bool)
+	// GetResources 按标识排序后分页返回，limit <= 0 表示不限制数量
+	GetResources(offset, limit int) []*apis.NodeResourceInfo
+	// Select 按标识排序后分页返回匹配选择器的资源，同时返回匹配总数
+	Select(selector apis.Selector, offset, limit int) ([]*apis.NodeResourceInfo, int)
+	// Replace 以给定的资源整体替换注册表内容，返回被移除的资源
+	Replace(resources []*apis.NodeResourceInfo) (removed []*apis.NodeResourceInfo)
+	// ExpireBefore 移除最后一次上报早于 deadline 的资源并返回
+	ExpireBefore(deadline time.Time) []*apis.NodeResourceInfo
+	Len() int
+
+	TaskStore
+}
+
+// MemoryRegistry 为基于内存的注册表实现，按城市、行业、企业、资源类型、数据中心建立前缀索引，
+// 前缀及选择器查询只访问匹配的子树，分页时按子树计数跳过，无需遍历全部资源
+type MemoryRegistry struct {
 	sync.RWMutex
 	Resources map[string]*apis.NodeResourceInfo
+
+	keys     map[string]string
+	lastSeen map[string]time.Time
+	index    *prefixIndex
+	tasks    memoryTaskStore
+}
+
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		Resources: make(map[string]*apis.NodeResourceInfo),
+		keys:      make(map[string]string),
+		lastSeen:  make(map[string]time.Time),
+		index:     newPrefixIndex(),
+		tasks: memoryTaskStore{
+			templates: make(map[string]*apis.TaskTemplate),
+			paths:     make(map[string][]*apis.TaskPath),
+		},
+	}
 }
 
-func (r *Registry) AddResource(resource *apis.NodeResourceInfo) {
+func (r *MemoryRegistry) AddResource(resource *apis.NodeResourceInfo) *apis.NodeResourceInfo {
 	r.Lock()
 	defer r.Unlock()
+
+	key := apis.ResourceKey(resource.ID)
+	var previous *apis.NodeResourceInfo
+	if id, ok := r.keys[key]; ok {
+		previous = r.Resources[id]
+		r.remove(id)
+	}
+
+	if _, ok := r.Resources[resource.ID]; !ok {
+		r.index.insert(resource)
+	}
 	r.Resources[resource.ID] = resource
+	r.keys[key] = resource.ID
+	r.lastSeen[resource.ID] = time.Now()
+	return previous
 }
 
-func (r *Registry) DeleteResource(id string) {
+func (r *MemoryRegistry) DeleteResource(id string) (*apis.NodeResourceInfo, bool) {
 	r.Lock()
 	defer r.Unlock()
-	delete(r.Resources, id)
+
+	resource, ok := r.Resources[id]
+	if ok {
+		r.remove(id)
+	}
+	return resource, ok
 }
 
-func (r *Registry) GetResources(offset, limit int) []*apis.NodeResourceInfo {
+func (r *MemoryRegistry) GetResource(id string) (*apis.NodeResourceInfo, bool) {
 	r.RLock()
 	defer r.RUnlock()
+	resource, ok := r.Resources[id]
+	return resource, ok
+}
 
-	resources := make([]*apis.NodeResourceInfo, 0, 1000)
-	for _, resource := range r.Resources {
-		resources = append(resources, resource)
+func (r *MemoryRegistry) GetResourceByKey(key string) (*apis.NodeResourceInfo, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	id, ok := r.keys[key]
+	if !ok {
+		return nil, false
 	}
+	return r.Resources[id], true
+}
+
+func (r *MemoryRegistry) GetResources(offset, limit int) []*apis.NodeResourceInfo {
+	resources, _ := r.Select(apis.Selector{}, offset, limit)
 	return resources
 }
 
-var registry = &Registry{
-	Resources: make(map[string]*apis.NodeResourceInfo),
+func (r *MemoryRegistry) Select(selector apis.Selector, offset, limit int) ([]*apis.NodeResourceInfo, int) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.index.query(selector, offset, limit)
+}
+
+func (r *MemoryRegistry) Replace(resources []*apis.NodeResourceInfo) []*apis.NodeResourceInfo {
+	r.Lock()
+	defer r.Unlock()
+
+	old := r.Resources
+	r.Resources = make(map[string]*apis.NodeResourceInfo, len(resources))
+	r.keys = make(map[string]string, len(resources))
+	r.lastSeen = make(map[string]time.Time, len(resources))
+	r.index = newPrefixIndex()
+
+	now := time.Now()
+	for _, resource := range resources {
+		key := apis.ResourceKey(resource.ID)
+		if id, ok := r.keys[key]; ok {
+			r.remove(id)
+		}
+		if _, ok := r.Resources[resource.ID]; !ok {
+			r.index.insert(resource)
+		}
+		r.Resources[resource.ID] = resource
+		r.keys[key] = resource.ID
+		r.lastSeen[resource.ID] = now
+	}
+
+	var removed []*apis.NodeResourceInfo
+	for id, resource := range old {
+		if _, ok := r.Resources[id]; !ok {
+			removed = append(removed, resource)
+		}
+	}
+	return removed
 }
+
+func (r *MemoryRegistry) ExpireBefore(deadline time.Time) []*apis.NodeResourceInfo {
+	r.Lock()
+	defer r.Unlock()
+
+	var expired []*apis.NodeResourceInfo
+	for id, seen := range r.lastSeen {
+		if seen.Before(deadline) {
+			expired = append(expired, r.Resources[id])
+			r.remove(id)
+		}
+	}
+	return expired
+}
+
+func (r *MemoryRegistry) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.Resources)
+}
+
+// remove 调用方需持有写锁
+func (r *MemoryRegistry) remove(id string) {
+	key := apis.ResourceKey(id)
+	if r.keys[key] == id {
+		delete(r.keys, key)
+	}
+	if resource, ok := r.Resources[id]; ok {
+		r.index.remove(resource)
+	}
+	delete(r.Resources, id)
+	delete(r.lastSeen, id)
+}
+
+var registry Registry = NewMemoryRegistry()
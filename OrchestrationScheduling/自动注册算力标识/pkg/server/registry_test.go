@@ -0,0 +1,281 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+var (
+	testCities     = []string{"1101", "1201", "3101", "3301", "4401", "4403", "5101", "6101"}
+	testIndustries = []string{"tc", "it", "bf", "go", "en"}
+	testAZs        = []string{"501", "502", "503", "504"}
+	testChipTypes  = []string{"00000", "00001", "00100"}
+)
+
+func testResourceID(rnd *rand.Rand, n int) string {
+	return testCities[rnd.Intn(len(testCities))] +
+		testIndustries[rnd.Intn(len(testIndustries))] +
+		fmt.Sprintf("%05d", 20001+rnd.Intn(40)) +
+		fmt.Sprintf("%03d", 401+rnd.Intn(3)) +
+		testAZs[rnd.Intn(len(testAZs))] +
+		"02602001601004" +
+		fmt.Sprintf("F%04dS%07dN%06dP%05d", rnd.Intn(1000), rnd.Intn(100000), 10000, rnd.Intn(1000)) +
+		"01" + "00" + fmt.Sprintf("%032b", n) +
+		testChipTypes[rnd.Intn(len(testChipTypes))] +
+		fmt.Sprintf("%08d", rnd.Intn(16)) +
+		"00001"
+}
+
+func newTestRegistry(n int) (*MemoryRegistry, []*apis.NodeResourceInfo) {
+	rnd := rand.New(rand.NewSource(1))
+	r := NewMemoryRegistry()
+	resources := make([]*apis.NodeResourceInfo, 0, n)
+	for i := 0; i < n; i++ {
+		resource := apis.ParseResourceInfo(testResourceID(rnd, i))
+		r.AddResource(resource)
+		resources = append(resources, resource)
+	}
+	return r, resources
+}
+
+func linearSelect(resources []*apis.NodeResourceInfo, selector apis.Selector) []string {
+	var ids []string
+	for _, resource := range resources {
+		if selector.Matches(resource) {
+			ids = append(ids, resource.ID)
+		}
+	}
+	return ids
+}
+
+func TestMemoryRegistrySelectMatchesLinearScan(t *testing.T) {
+	r, _ := newTestRegistry(5000)
+	all := r.GetResources(0, 0)
+	for i := 1; i < len(all); i++ {
+		if all[i-1].ID >= all[i].ID {
+			t.Fatalf("resources are not sorted at %d", i)
+		}
+	}
+
+	selectors := []string{
+		"",
+		"city=1101",
+		"enterprise=20005",
+		"data-center=502",
+		"city=3101,resource-type=402,resource-az=501",
+		"chip-type=00100",
+		"industry=tc,chip-model=00000003",
+	}
+	prefixes := []string{"", "11", "1101tc", "4403it2001", "1101tc20001401501", "3101bf20010402503026"}
+
+	for _, s := range selectors {
+		for _, prefix := range prefixes {
+			selector, err := apis.ParseSelector(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			selector.Prefix = prefix
+			expected := linearSelect(all, selector)
+
+			for _, window := range [][2]int{{0, 0}, {0, 10}, {7, 25}, {len(expected) - 1, 5}} {
+				got, total := r.Select(selector, window[0], window[1])
+				if total != len(expected) {
+					t.Fatalf("%s: expected total %d, got %d", selector, len(expected), total)
+				}
+
+				start, end := window[0], len(expected)
+				if start < 0 {
+					start = 0
+				}
+				if start > end {
+					start = end
+				}
+				if window[1] > 0 && start+window[1] < end {
+					end = start + window[1]
+				}
+				if len(got) != end-start {
+					t.Fatalf("%s %v: expected %d results, got %d", selector, window, end-start, len(got))
+				}
+				for i, resource := range got {
+					if resource.ID != expected[start+i] {
+						t.Fatalf("%s %v: result %d mismatch", selector, window, i)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestMemoryRegistryReplacesSameChip(t *testing.T) {
+	r, resources := newTestRegistry(10)
+	old := resources[3]
+
+	id := old.ID[:31] + "F9999" + old.ID[36:]
+	previous := r.AddResource(apis.ParseResourceInfo(id))
+	if previous == nil || previous.ID != old.ID {
+		t.Fatalf("expected %s to be replaced", old.ID)
+	}
+	if _, ok := r.GetResource(old.ID); ok {
+		t.Fatalf("expected %s to be removed", old.ID)
+	}
+	if r.Len() != 10 {
+		t.Fatalf("expected 10 resources, got %d", r.Len())
+	}
+
+	selector := apis.Selector{Prefix: id}
+	if got, total := r.Select(selector, 0, 0); total != 1 || got[0].ID != id {
+		t.Fatalf("expected to find %s through the index", id)
+	}
+
+	r.DeleteResource(id)
+	if _, total := r.Select(apis.Selector{Prefix: id[:17]}, 0, 0); total != len(linearSelect(r.GetResources(0, 0), apis.Selector{Prefix: id[:17]})) {
+		t.Fatalf("index count is out of sync after delete")
+	}
+}
+
+func TestMemoryRegistryConcurrentSelect(t *testing.T) {
+	r, resources := newTestRegistry(200)
+	rnd := rand.New(rand.NewSource(2))
+
+	// 查询并发重建叶子节点的排序结果，同时有注册与注销，配合 -race 检查
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				r.Select(apis.Selector{Prefix: "1101"}, 0, 10)
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		r.DeleteResource(resources[i].ID)
+		r.AddResource(apis.ParseResourceInfo(testResourceID(rnd, 1000+i)))
+	}
+	wg.Wait()
+
+	if all, total := r.Select(apis.Selector{}, 0, 0); total != 200 || len(all) != 200 {
+		t.Fatalf("expected 200 resources, got %d", total)
+	}
+}
+
+func TestMemoryRegistryShortID(t *testing.T) {
+	r := NewMemoryRegistry()
+	// 未经校验的过短标识不应使索引越界
+	r.AddResource(&apis.NodeResourceInfo{ID: "1101tc"})
+	if got, total := r.Select(apis.Selector{}, 0, 0); total != 1 || got[0].ID != "1101tc" {
+		t.Fatalf("short id not listed: %d", total)
+	}
+	if _, ok := r.DeleteResource("1101tc"); !ok || r.Len() != 0 {
+		t.Fatal("short id not removed")
+	}
+	if _, total := r.Select(apis.Selector{}, 0, 0); total != 0 {
+		t.Fatalf("index count is out of sync after delete: %d", total)
+	}
+}
+
+func TestTenantScopeSelectMatchesLinearScan(t *testing.T) {
+	r, _ := newTestRegistry(5000)
+	saved := registry
+	registry = r
+	defer func() { registry = saved }()
+
+	all := r.GetResources(0, 0)
+	scope := tenantScope{enterprises: []string{"20003", "20011", "20027"}}
+	selector, _ := apis.ParseSelector("city=1101")
+
+	var expected []string
+	for _, id := range linearSelect(all, selector) {
+		if scope.allows(id) {
+			expected = append(expected, id)
+		}
+	}
+
+	for _, window := range [][2]int{{0, 0}, {0, 10}, {5, 7}} {
+		got, total := scope.selectResources(selector, window[0], window[1])
+		if total != len(expected) {
+			t.Fatalf("%v: expected total %d, got %d", window, len(expected), total)
+		}
+		for i, resource := range got {
+			if resource.ID != expected[window[0]+i] {
+				t.Fatalf("%v: result %d mismatch", window, i)
+			}
+		}
+	}
+
+	selector.Fields[apis.FieldCompany] = "20004"
+	if _, total := scope.selectResources(selector, 0, 0); total != 0 {
+		t.Fatalf("expected enterprise outside the tenant to be hidden, got %d", total)
+	}
+}
+
+const benchmarkRegistrySize = 1000000
+
+var (
+	benchmarkOnce      sync.Once
+	benchmarkRegistry  *MemoryRegistry
+	benchmarkResources []*apis.NodeResourceInfo
+)
+
+func loadBenchmarkRegistry(b *testing.B) (*MemoryRegistry, []*apis.NodeResourceInfo) {
+	benchmarkOnce.Do(func() {
+		benchmarkRegistry, benchmarkResources = newTestRegistry(benchmarkRegistrySize)
+		// 预先构建叶子节点的排序结果，避免首次查询的开销计入基准
+		benchmarkRegistry.GetResources(0, 1)
+	})
+	b.ResetTimer()
+	return benchmarkRegistry, benchmarkResources
+}
+
+func benchmarkSelect(b *testing.B, s, prefix string, offset, limit int) {
+	r, _ := loadBenchmarkRegistry(b)
+	selector, err := apis.ParseSelector(s)
+	if err != nil {
+		b.Fatal(err)
+	}
+	selector.Prefix = prefix
+
+	for i := 0; i < b.N; i++ {
+		r.Select(selector, offset, limit)
+	}
+}
+
+func BenchmarkRegistryListPage(b *testing.B) {
+	benchmarkSelect(b, "", "", 500000, 100)
+}
+
+func BenchmarkRegistrySelectCity(b *testing.B) {
+	benchmarkSelect(b, "city=3101", "", 0, 100)
+}
+
+func BenchmarkRegistrySelectEnterprise(b *testing.B) {
+	benchmarkSelect(b, "enterprise=20005", "", 0, 100)
+}
+
+func BenchmarkRegistrySelectDataCenterPrefix(b *testing.B) {
+	benchmarkSelect(b, "", "1101tc20001401501", 0, 100)
+}
+
+func BenchmarkRegistrySelectEnterpriseChipType(b *testing.B) {
+	benchmarkSelect(b, "enterprise=20005,chip-type=00100", "", 0, 100)
+}
+
+// BenchmarkLinearScanSelectEnterprise 为不使用索引时逐条匹配的基线
+func BenchmarkLinearScanSelectEnterprise(b *testing.B) {
+	_, resources := loadBenchmarkRegistry(b)
+	selector, _ := apis.ParseSelector("enterprise=20005")
+
+	for i := 0; i < b.N; i++ {
+		var matched []*apis.NodeResourceInfo
+		for _, resource := range resources {
+			if strings.HasPrefix(resource.ID, selector.Prefix) && selector.Matches(resource) {
+				matched = append(matched, resource)
+			}
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+const (
+	SnapshotSchemaVersion = 1
+	SnapshotKind          = "cpid-registry-snapshot"
+
+	SnapshotFormatJSONL = "jsonl"
+	SnapshotFormatCSV   = "csv"
+
+	SnapshotModeMerge   = "merge"
+	SnapshotModeReplace = "replace"
+
+	// SnapshotSchemaHeader 为快照导出、导入时携带 schema 版本的 HTTP 头
+	SnapshotSchemaHeader = "X-Snapshot-Schema-Version"
+)
+
+// SnapshotHeader 为 JSON Lines 快照的首行
+type SnapshotHeader struct {
+	Kind          string    `json:"kind"`
+	SchemaVersion int       `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Count         int       `json:"count"`
+}
+
+// SnapshotRecord 为快照中的一条记录，除 id 外的字段均由 id 解码而来，导入时仅以 id 为准
+type SnapshotRecord struct {
+	ID                   string `json:"id"`
+	City                 string `json:"city"`
+	CompanyType          string `json:"company_type"`
+	Company              string `json:"company"`
+	ResourceType         string `json:"resource_type"`
+	ResourceAZ           string `json:"resource_az"`
+	ServiceType          string `json:"service_type"`
+	ComputePFLOPs        int64  `json:"compute_pflops"`
+	StorageGB            int64  `json:"storage_gb"`
+	BandwidthMbps        int64  `json:"bandwidth_mbps"`
+	PowerWatts           int64  `json:"power_watts"`
+	NetworkType          string `json:"network_type"`
+	PowerResourceAddress string `json:"power_resource_address"`
+	ChipType             string `json:"chip_type"`
+	ChipModel            string `json:"chip_model"`
+	ChipUniqNumber       string `json:"chip_uniq_number"`
+}
+
+var snapshotColumns = []string{
+	"id", "city", "company_type", "company", "resource_type", "resource_az", "service_type",
+	"compute_pflops", "storage_gb", "bandwidth_mbps", "power_watts", "network_type",
+	"power_resource_address", "chip_type", "chip_model", "chip_uniq_number",
+}
+
+func newSnapshotRecord(resource *apis.NodeResourceInfo) SnapshotRecord {
+	return SnapshotRecord{
+		ID:                   resource.ID,
+		City:                 resource.City,
+		CompanyType:          resource.CompanyType,
+		Company:              resource.Company,
+		ResourceType:         resource.ResourceType,
+		ResourceAZ:           resource.ResourceAZ,
+		ServiceType:          resource.ServiceType,
+		ComputePFLOPs:        resource.ComputePFLOPs(),
+		StorageGB:            resource.StorageGB(),
+		BandwidthMbps:        resource.BandwidthMbps(),
+		PowerWatts:           resource.PowerWatts(),
+		NetworkType:          resource.NetworkType,
+		PowerResourceAddress: resource.PowerResourceAddress,
+		ChipType:             resource.ChipType,
+		ChipModel:            resource.ChipModel,
+		ChipUniqNumber:       resource.ChipUniqNumber,
+	}
+}
+
+func (s SnapshotRecord) columns() []string {
+	return []string{
+		s.ID, s.City, s.CompanyType, s.Company, s.ResourceType, s.ResourceAZ, s.ServiceType,
+		strconv.FormatInt(s.ComputePFLOPs, 10), strconv.FormatInt(s.StorageGB, 10),
+		strconv.FormatInt(s.BandwidthMbps, 10), strconv.FormatInt(s.PowerWatts, 10), s.NetworkType,
+		s.PowerResourceAddress, s.ChipType, s.ChipModel, s.ChipUniqNumber,
+	}
+}
+
+// WriteSnapshot 将注册表内容按指定格式写出
+func WriteSnapshot(w io.Writer, format string, resources []*apis.NodeResourceInfo) error {
+	switch format {
+	case SnapshotFormatJSONL:
+		encoder := json.NewEncoder(w)
+		header := SnapshotHeader{
+			Kind:          SnapshotKind,
+			SchemaVersion: SnapshotSchemaVersion,
+			ExportedAt:    time.Now().UTC(),
+			Count:         len(resources),
+		}
+		if err := encoder.Encode(header); err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if err := encoder.Encode(newSnapshotRecord(resource)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case SnapshotFormatCSV:
+		if _, err := fmt.Fprintf(w, "#schema_version=%d\n", SnapshotSchemaVersion); err != nil {
+			return err
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.Write(snapshotColumns); err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if err := writer.Write(newSnapshotRecord(resource).columns()); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unsupported snapshot format %q", format)
+	}
+}
+
+// ReadSnapshot 解析快照并校验其中的算力标识
+func ReadSnapshot(r io.Reader, format string) ([]*apis.NodeResourceInfo, error) {
+	var ids []string
+	var err error
+
+	switch format {
+	case SnapshotFormatJSONL:
+		ids, err = readJSONLSnapshot(r)
+	case SnapshotFormatCSV:
+		ids, err = readCSVSnapshot(r)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*apis.NodeResourceInfo, 0, len(ids))
+	for i, id := range ids {
+		if err := apis.ValidateResourceID(id); err != nil {
+			return nil, fmt.Errorf("record %d: %v", i+1, err)
+		}
+		resources = append(resources, apis.ParseResourceInfo(id))
+	}
+	return resources, nil
+}
+
+func readJSONLSnapshot(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("snapshot is empty")
+	}
+
+	var header SnapshotHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("error parsing snapshot header: %v", err)
+	}
+	if header.Kind != SnapshotKind {
+		return nil, fmt.Errorf("unexpected snapshot kind %q", header.Kind)
+	}
+	if err := checkSnapshotSchemaVersion(header.SchemaVersion); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	line := 1
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record SnapshotRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("error parsing snapshot line %d: %v", line, err)
+		}
+		ids = append(ids, record.ID)
+	}
+	return ids, scanner.Err()
+}
+
+func readCSVSnapshot(r io.Reader) ([]string, error) {
+	reader := bufio.NewReader(r)
+
+	first, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	first = strings.TrimSpace(first)
+	if !strings.HasPrefix(first, "#schema_version=") {
+		return nil, fmt.Errorf("snapshot is missing the #schema_version line")
+	}
+	version := strings.TrimPrefix(first, "#schema_version=")
+	schemaVersion, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot schema version %q", version)
+	}
+	if err := checkSnapshotSchemaVersion(schemaVersion); err != nil {
+		return nil, err
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot columns: %v", err)
+	}
+	idColumn := -1
+	for i, column := range header {
+		if column == "id" {
+			idColumn = i
+		}
+	}
+	if idColumn < 0 {
+		return nil, fmt.Errorf("snapshot has no id column")
+	}
+
+	var ids []string
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, row[idColumn])
+	}
+	return ids, nil
+}
+
+func checkSnapshotSchemaVersion(version int) error {
+	if version < 1 || version > SnapshotSchemaVersion {
+		return fmt.Errorf("unsupported snapshot schema version %d, this server supports up to %d",
+			version, SnapshotSchemaVersion)
+	}
+	return nil
+}
+
+func snapshotFormat(r *http.Request) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = SnapshotFormatJSONL
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = SnapshotFormatCSV
+		}
+	}
+	if format != SnapshotFormatJSONL && format != SnapshotFormatCSV {
+		return "", fmt.Errorf("unsupported snapshot format %q", format)
+	}
+	return format, nil
+}
+
+func ExportSnapshot(w http.ResponseWriter, r *http.Request) {
+	format, err := snapshotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == SnapshotFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(SnapshotSchemaHeader, strconv.Itoa(SnapshotSchemaVersion))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=registry-%s.%s",
+		time.Now().UTC().Format("20060102150405"), format))
+
+	resources, _ := requestScope(r).selectResources(apis.Selector{}, 0, 0)
+	if err := WriteSnapshot(w, format, resources); err != nil {
+		fmt.Printf("Error writing snapshot: %v\n", err)
+	}
+}
+
+func ImportSnapshot(w http.ResponseWriter, r *http.Request) {
+	format, err := snapshotFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = SnapshotModeMerge
+	}
+	if mode != SnapshotModeMerge && mode != SnapshotModeReplace {
+		http.Error(w, fmt.Sprintf("unsupported import mode %q", mode), http.StatusBadRequest)
+		return
+	}
+
+	if version := r.Header.Get(SnapshotSchemaHeader); version != "" {
+		schemaVersion, err := strconv.Atoi(version)
+		if err == nil {
+			err = checkSnapshotSchemaVersion(schemaVersion)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resources, err := ReadSnapshot(r.Body, format)
+	if err != nil {
+		requestErrors.Inc("import")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 整体替换会移除其他租户的资源，仅允许 admin 执行
+	scope := requestScope(r)
+	if mode == SnapshotModeReplace && !scope.all {
+		requestErrors.Inc("tenant")
+		http.Error(w, "replace mode requires the admin role", http.StatusForbidden)
+		return
+	}
+	for _, resource := range resources {
+		if !scope.allows(resource.ID) {
+			writeForbidden(w, resource.ID)
+			return
+		}
+	}
+
+	if mode == SnapshotModeReplace {
+		var added []*apis.NodeResourceInfo
+		byKey := make(map[string]*apis.NodeResourceInfo, len(resources))
+		for _, resource := range resources {
+			if _, ok := registry.GetResource(resource.ID); !ok {
+				added = append(added, resource)
+			}
+			byKey[apis.ResourceKey(resource.ID)] = resource
+		}
+		// 同一芯片的标识变化记为 update，与逐个注册时一致
+		updated := make(map[string]bool)
+		for _, resource := range registry.Replace(resources) {
+			if after, ok := byKey[apis.ResourceKey(resource.ID)]; ok {
+				updated[after.ID] = true
+				recordChange(r, AuditActionUpdate, resource, after)
+			} else {
+				recordChange(r, AuditActionUnregister, resource, nil)
+			}
+		}
+		for _, resource := range added {
+			if !updated[resource.ID] {
+				recordChange(r, AuditActionRegister, nil, resource)
+			}
+		}
+	} else {
+		for _, resource := range resources {
+			addResource(r, resource)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mode":     mode,
+		"imported": len(resources),
+		"total":    registry.Len(),
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	_, resources := newTestRegistry(50)
+	for _, format := range []string{SnapshotFormatJSONL, SnapshotFormatCSV} {
+		var buf bytes.Buffer
+		if err := WriteSnapshot(&buf, format, resources); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadSnapshot(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(read, resources) {
+			t.Fatalf("%s: round trip changed the resources", format)
+		}
+	}
+
+	// 空注册表的快照同样可以导入
+	var buf bytes.Buffer
+	WriteSnapshot(&buf, SnapshotFormatCSV, nil)
+	if read, err := ReadSnapshot(&buf, SnapshotFormatCSV); err != nil || len(read) != 0 {
+		t.Fatalf("empty snapshot: %v %v", read, err)
+	}
+}
+
+func TestReadSnapshotRejectsMalformedInput(t *testing.T) {
+	id := testResourceID(rand.New(rand.NewSource(1)), 1)
+	header := `{"kind":"cpid-registry-snapshot","schema_version":1}`
+	for name, tc := range map[string]struct{ format, data string }{
+		"empty jsonl":      {SnapshotFormatJSONL, ""},
+		"header not json":  {SnapshotFormatJSONL, "id\n"},
+		"wrong kind":       {SnapshotFormatJSONL, `{"kind":"other","schema_version":1}` + "\n"},
+		"future schema":    {SnapshotFormatJSONL, `{"kind":"cpid-registry-snapshot","schema_version":2}` + "\n"},
+		"malformed record": {SnapshotFormatJSONL, header + "\n{\"id\":\n"},
+		"invalid id":       {SnapshotFormatJSONL, header + "\n" + `{"id":"1101"}` + "\n"},
+		"missing schema":   {SnapshotFormatCSV, "id\n" + id + "\n"},
+		"bad schema":       {SnapshotFormatCSV, "#schema_version=x\nid\n"},
+		"no id column":     {SnapshotFormatCSV, "#schema_version=1\ncity\n1101\n"},
+		"short row":        {SnapshotFormatCSV, "#schema_version=1\nid,city\n" + id + "\n"},
+		"invalid csv id":   {SnapshotFormatCSV, "#schema_version=1\nid\n" + id[:50] + "\n"},
+		"unsupported":      {"xml", ""},
+	} {
+		if _, err := ReadSnapshot(strings.NewReader(tc.data), tc.format); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	// 空行被忽略，除 id 外的列不参与导入
+	data := header + "\n\n" + `{"id":"` + id + `","city":"9999"}` + "\n"
+	if read, err := ReadSnapshot(strings.NewReader(data), SnapshotFormatJSONL); err != nil || len(read) != 1 || read[0].City != id[:4] {
+		t.Fatalf("unexpected records %v %v", read, err)
+	}
+}
+
+func TestSnapshotExportImport(t *testing.T) {
+	withLimits(t, nil)
+	_, resources := newTestRegistry(20)
+	for _, resource := range resources {
+		registry.AddResource(resource)
+	}
+
+	w := httptest.NewRecorder()
+	ExportSnapshot(w, httptest.NewRequest(http.MethodGet, "/v1/snapshot?format=csv", nil))
+	if w.Code != http.StatusOK || w.Header().Get(SnapshotSchemaHeader) != "1" || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("unexpected export %d %v", w.Code, w.Header())
+	}
+	exported := w.Body.String()
+
+	importSnapshot := func(url, contentType, body string) (int, map[string]interface{}) {
+		r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		ImportSnapshot(w, r)
+		var result map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&result)
+		return w.Code, result
+	}
+
+	// replace 以快照内容替换注册表，多出的资源被移除
+	extra := matchResourceID(9, 1, 100, apis.NetworkTypeIB, "00001")
+	registry.AddResource(apis.ParseResourceInfo(extra))
+	code, result := importSnapshot("/v1/snapshot?mode=replace", "text/csv", exported)
+	if code != http.StatusOK || result["imported"] != float64(20) || result["total"] != float64(20) {
+		t.Fatalf("unexpected replace result %d %v", code, result)
+	}
+	if _, ok := registry.GetResource(extra); ok {
+		t.Fatal("replace kept a resource missing from the snapshot")
+	}
+
+	// merge 只新增或刷新
+	registry.AddResource(apis.ParseResourceInfo(extra))
+	if code, result := importSnapshot("/v1/snapshot", "text/csv", exported); code != http.StatusOK || result["total"] != float64(21) {
+		t.Fatalf("unexpected merge result %d %v", code, result)
+	}
+
+	// 格式错误的快照不修改注册表
+	malformed := exported + "1101\n"
+	if code, _ := importSnapshot("/v1/snapshot?mode=replace", "text/csv", malformed); code != http.StatusBadRequest || registry.Len() != 21 {
+		t.Fatalf("malformed snapshot: got %d, %d resources", code, registry.Len())
+	}
+	for _, url := range []string{"/v1/snapshot?mode=append", "/v1/snapshot?format=xml"} {
+		if code, _ := importSnapshot(url, "text/csv", exported); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", url, code)
+		}
+	}
+	r := httptest.NewRequest(http.MethodPost, "/v1/snapshot", strings.NewReader(exported))
+	r.Header.Set("Content-Type", "text/csv")
+	r.Header.Set(SnapshotSchemaHeader, "2")
+	w = httptest.NewRecorder()
+	ImportSnapshot(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("future schema header: got %d", w.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+
+	"register-power-resources/pkg/apis"
+)
+
+// Stats 返回调用方范围内注册表的汇总
+func Stats(w http.ResponseWriter, r *http.Request) {
+	revision := watchEvents.currentRevision()
+	resources, total := requestScope(r).selectResources(apis.Selector{}, 0, 0)
+
+	stats := apis.RegistryStats{
+		Resources:   total,
+		ChipTypes:   map[string]int{},
+		Cities:      map[string]int{},
+		Enterprises: map[string]int{},
+		Revision:    revision,
+	}
+	servers := map[string]bool{}
+	dataCenters := map[string]bool{}
+	for _, resource := range resources {
+		stats.ChipTypes[apis.CodeName(apis.ChipTypeNames, resource.ChipType)]++
+		stats.Cities[resource.City]++
+		stats.Enterprises[resource.Company]++
+
+		// 计算量、功耗为芯片所在服务器的总量，存储、带宽为数据中心的总量，与容量指标相同分别去重
+		if !servers[serverKey(resource)] {
+			servers[serverKey(resource)] = true
+			stats.ComputePFLOPs += float64(resource.ComputePFLOPs())
+			stats.PowerWatts += resource.PowerWatts()
+		}
+		if !dataCenters[dataCenterKey(resource)] {
+			dataCenters[dataCenterKey(resource)] = true
+			stats.StorageGB += resource.StorageGB()
+			stats.BandwidthMbps += resource.BandwidthMbps()
+		}
+	}
+	stats.Servers = len(servers)
+
+	writeJSON(w, http.StatusOK, stats)
+}
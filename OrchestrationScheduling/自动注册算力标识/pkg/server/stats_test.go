@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"register-power-resources/pkg/apis"
+)
+
+func TestStats(t *testing.T) {
+	withLimits(t, nil)
+	for _, id := range []string{
+		// 同一服务器上的两张芯片，服务器容量只计一次；同一数据中心的存储、带宽只计一次
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001"),
+		matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00002"),
+		matchResourceID(2, 4, 1000, apis.NetworkTypeIB, "00001"),
+	} {
+		registry.AddResource(apis.ParseResourceInfo(id))
+	}
+
+	w := httptest.NewRecorder()
+	Stats(w, httptest.NewRequest(http.MethodGet, "/v1/stats", nil))
+	var stats apis.RegistryStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Resources != 3 || stats.Servers != 2 || stats.ComputePFLOPs != 12 || stats.StorageGB != 1000 ||
+		stats.BandwidthMbps != 10000 || stats.PowerWatts != 3000 {
+		t.Fatalf("unexpected totals %+v", stats)
+	}
+	if stats.ChipTypes["gpu"] != 3 || stats.Cities["1101"] != 3 || stats.Enterprises["20001"] != 3 {
+		t.Fatalf("unexpected breakdown %+v", stats)
+	}
+
+	// 租户只能看到本企业的资源
+	EnableTenancy(&ClientsConfig{Clients: map[string]ClientConfig{"cluster-b": {Enterprises: []string{"20002"}}}})
+	defer EnableTenancy(nil)
+	r := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	r.TLS = withClientCert("cluster-b")
+	w = httptest.NewRecorder()
+	Stats(w, r)
+	stats = apis.RegistryStats{}
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.Resources != 0 || stats.Servers != 0 || stats.ComputePFLOPs != 0 {
+		t.Fatalf("tenant sees other enterprises: %+v", stats)
+	}
+}
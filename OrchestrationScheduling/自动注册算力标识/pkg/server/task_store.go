@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"register-power-resources/pkg/apis"
+)
+
+// maxTaskPathsPerTemplate 为每个任务模板保留的最近任务路径数
+const maxTaskPathsPerTemplate = 1000
+
+// TaskStore 保存任务模板及任务路径反馈，与注册表使用同一存储后端
+type TaskStore interface {
+	AddTaskTemplate(template *apis.TaskTemplate)
+	GetTaskTemplate(id string) (*apis.TaskTemplate, bool)
+	AddTaskPath(path *apis.TaskPath)
+	// GetTaskPaths 按上报顺序返回任务模板的路径反馈
+	GetTaskPaths(templateID string) []*apis.TaskPath
+}
+
+type memoryTaskStore struct {
+	templates map[string]*apis.TaskTemplate
+	paths     map[string][]*apis.TaskPath
+}
+
+func (r *MemoryRegistry) AddTaskTemplate(template *apis.TaskTemplate) {
+	r.Lock()
+	defer r.Unlock()
+	r.tasks.templates[template.ID] = template
+}
+
+func (r *MemoryRegistry) GetTaskTemplate(id string) (*apis.TaskTemplate, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	template, ok := r.tasks.templates[id]
+	return template, ok
+}
+
+func (r *MemoryRegistry) AddTaskPath(path *apis.TaskPath) {
+	r.Lock()
+	defer r.Unlock()
+	paths := append(r.tasks.paths[path.TemplateID], path)
+	if len(paths) > maxTaskPathsPerTemplate {
+		paths = paths[len(paths)-maxTaskPathsPerTemplate:]
+	}
+	r.tasks.paths[path.TemplateID] = paths
+}
+
+func (r *MemoryRegistry) GetTaskPaths(templateID string) []*apis.TaskPath {
+	r.RLock()
+	defer r.RUnlock()
+	return append([]*apis.TaskPath{}, r.tasks.paths[templateID]...)
+}
+
+// taskIDSource 为任务模板 id 的随机源
+var taskIDSource io.Reader = rand.Reader
+
+func newTaskID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(taskIDSource, b); err != nil {
+		return "", fmt.Errorf("error generating task template id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
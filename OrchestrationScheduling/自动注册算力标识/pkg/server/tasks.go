@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"register-power-resources/pkg/apis"
+
+	"github.com/gorilla/mux"
+)
+
+// pathHistory 汇总一组芯片在某个任务模板上的历史阶段执行结果
+type pathHistory struct {
+	succeeded int
+	failed    int
+	seconds   float64
+}
+
+func (h *pathHistory) averageDuration() time.Duration {
+	runs := h.succeeded + h.failed
+	if runs == 0 {
+		return 0
+	}
+	return (time.Duration(h.seconds/float64(runs)) * time.Second).Round(time.Second)
+}
+
+// templateHistory 按匹配分组汇总任务模板的路径反馈，取消的阶段不计入
+func templateHistory(templateID string) map[string]*pathHistory {
+	history := map[string]*pathHistory{}
+	for _, path := range registry.GetTaskPaths(templateID) {
+		for _, stage := range path.Stages {
+			if stage.Outcome != apis.TaskOutcomeSucceeded && stage.Outcome != apis.TaskOutcomeFailed {
+				continue
+			}
+			// 同一阶段在同一组芯片上只计一次
+			seen := map[string]bool{}
+			for _, id := range stage.ComputeIDs {
+				key := matchKey(apis.ParseResourceInfo(id))
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				h, ok := history[key]
+				if !ok {
+					h = &pathHistory{}
+					history[key] = h
+				}
+				if stage.Outcome == apis.TaskOutcomeSucceeded {
+					h.succeeded++
+				} else {
+					h.failed++
+				}
+				h.seconds += stage.DurationSeconds
+			}
+		}
+	}
+	return history
+}
+
+func validOutcome(outcome string) bool {
+	switch outcome {
+	case apis.TaskOutcomeSucceeded, apis.TaskOutcomeFailed, apis.TaskOutcomeCancelled:
+		return true
+	}
+	return false
+}
+
+func validateTaskPath(path *apis.TaskPath) error {
+	if !validOutcome(path.Outcome) {
+		return fmt.Errorf("invalid task outcome %q", path.Outcome)
+	}
+	if len(path.Stages) == 0 {
+		return errors.New("task path must contain at least one stage")
+	}
+	for i, stage := range path.Stages {
+		if !validOutcome(stage.Outcome) {
+			return fmt.Errorf("stage %d: invalid outcome %q", i, stage.Outcome)
+		}
+		if stage.DurationSeconds < 0 {
+			return fmt.Errorf("stage %d: duration must not be negative", i)
+		}
+		if len(stage.ComputeIDs) == 0 {
+			return fmt.Errorf("stage %d: compute_ids must not be empty", i)
+		}
+		for _, id := range stage.ComputeIDs {
+			if err := apis.ValidateResourceID(id); err != nil {
+				return fmt.Errorf("stage %d: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// visibleTemplate 返回调用方可访问的任务模板。开启租户隔离时非 admin 客户端只能访问自己创建的模板，
+// 其他客户端的模板按不存在处理，不泄露其是否存在
+func visibleTemplate(r *http.Request) (*apis.TaskTemplate, bool) {
+	template, ok := registry.GetTaskTemplate(mux.Vars(r)["id"])
+	if !ok || !(requestScope(r).all || template.Owner == callerIdentity(r)) {
+		return nil, false
+	}
+	return template, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func CreateTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	var template apis.TaskTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		requestErrors.Inc("task_template")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// 提前校验匹配条件，避免保存无法匹配的模板
+	if _, _, err := parseMatchRequest(template.Requirements); err != nil {
+		requestErrors.Inc("task_template")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := newTaskID()
+	if err != nil {
+		requestErrors.Inc("task_template")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	template.ID = id
+	template.Owner = callerIdentity(r)
+	template.CreatedAt = time.Now().UTC()
+	registry.AddTaskTemplate(&template)
+
+	w.Header().Set("Location", "/v1/templates/"+template.ID)
+	writeJSON(w, http.StatusCreated, template)
+}
+
+func GetTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := visibleTemplate(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// MatchTaskTemplate 按任务模板中的需求匹配资源，并结合该模板的任务路径历史调整得分
+func MatchTaskTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := visibleTemplate(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	response, err := matchResources(template.Requirements, templateHistory(template.ID), requestScope(r))
+	if err != nil {
+		requestErrors.Inc("match")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func CreateTaskPath(w http.ResponseWriter, r *http.Request) {
+	template, ok := visibleTemplate(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var path apis.TaskPath
+	if err := json.NewDecoder(r.Body).Decode(&path); err != nil {
+		requestErrors.Inc("task_path")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateTaskPath(&path); err != nil {
+		requestErrors.Inc("task_path")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scope := requestScope(r)
+	for _, stage := range path.Stages {
+		if id, forbidden := scope.forbidden(stage.ComputeIDs); forbidden {
+			writeForbidden(w, id)
+			return
+		}
+	}
+
+	path.TemplateID = template.ID
+	path.ReportedAt = time.Now().UTC()
+	registry.AddTaskPath(&path)
+	writeJSON(w, http.StatusCreated, path)
+}
+
+func ListTaskPaths(w http.ResponseWriter, r *http.Request) {
+	template, ok := visibleTemplate(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, registry.GetTaskPaths(template.ID))
+}
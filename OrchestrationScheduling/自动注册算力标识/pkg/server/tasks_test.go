@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"register-power-resources/pkg/apis"
+)
+
+func taskRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/templates", CreateTaskTemplate).Methods("POST")
+	router.HandleFunc("/v1/templates/{id}", GetTaskTemplate).Methods("GET")
+	router.HandleFunc("/v1/templates/{id}/matches", MatchTaskTemplate).Methods("GET")
+	router.HandleFunc("/v1/templates/{id}/paths", CreateTaskPath).Methods("POST")
+	router.HandleFunc("/v1/templates/{id}/paths", ListTaskPaths).Methods("GET")
+	return router
+}
+
+func serveTask(router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return w
+}
+
+func TestCreateTaskTemplateValidatesRequirements(t *testing.T) {
+	withLimits(t, nil)
+	router := taskRouter()
+
+	for _, requirements := range []string{
+		`{"chip_type":"tpu"}`,
+		`{"chip_type":"001"}`,
+		`{"network_type":"wifi"}`,
+		`{"selector":"unknown=1"}`,
+	} {
+		if w := serveTask(router, http.MethodPost, "/v1/templates", `{"requirements":`+requirements+`}`); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", requirements, w.Code)
+		}
+	}
+
+	w := serveTask(router, http.MethodPost, "/v1/templates", `{"source":"platform","requirements":{"chip_type":"gpu","network_type":"ib"}}`)
+	var template apis.TaskTemplate
+	if w.Code != http.StatusCreated || json.NewDecoder(w.Body).Decode(&template) != nil || len(template.ID) != 16 ||
+		w.Header().Get("Location") != "/v1/templates/"+template.ID {
+		t.Fatalf("unexpected response %d %+v", w.Code, template)
+	}
+	if w := serveTask(router, http.MethodGet, "/v1/templates/"+template.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("template not found: %d", w.Code)
+	}
+
+	// 随机源出错时返回 500 而不是 panic
+	saved := taskIDSource
+	taskIDSource = strings.NewReader("")
+	defer func() { taskIDSource = saved }()
+	if w := serveTask(router, http.MethodPost, "/v1/templates", `{"requirements":{}}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d without randomness, want 500", w.Code)
+	}
+}
+
+func TestTaskPathHistoryAdjustsScore(t *testing.T) {
+	withLimits(t, nil)
+	router := taskRouter()
+	good := matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001")
+	bad := matchResourceID(2, 8, 2000, apis.NetworkTypeIB, "00001")
+	registry.AddResource(apis.ParseResourceInfo(good))
+	registry.AddResource(apis.ParseResourceInfo(bad))
+
+	w := serveTask(router, http.MethodPost, "/v1/templates", `{"requirements":{"chip_type":"gpu"}}`)
+	var template apis.TaskTemplate
+	json.NewDecoder(w.Body).Decode(&template)
+	paths := "/v1/templates/" + template.ID + "/paths"
+
+	for _, body := range []string{
+		`{"outcome":"done","stages":[]}`,
+		`{"outcome":"succeeded","stages":[]}`,
+		`{"outcome":"succeeded","stages":[{"outcome":"succeeded","compute_ids":["invalid"]}]}`,
+		`{"outcome":"succeeded","stages":[{"outcome":"succeeded","duration_seconds":-1,"compute_ids":["` + good + `"]}]}`,
+	} {
+		if w := serveTask(router, http.MethodPost, paths, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", body, w.Code)
+		}
+	}
+	for _, body := range []string{
+		fmt.Sprintf(`{"outcome":"succeeded","stages":[{"outcome":"succeeded","duration_seconds":60,"compute_ids":["%s"]}]}`, good),
+		fmt.Sprintf(`{"outcome":"failed","stages":[{"outcome":"failed","duration_seconds":60,"compute_ids":["%s"]}]}`, bad),
+	} {
+		if w := serveTask(router, http.MethodPost, paths, body); w.Code != http.StatusCreated {
+			t.Fatalf("%s: got %d %s", body, w.Code, w.Body)
+		}
+	}
+	if w := serveTask(router, http.MethodPost, "/v1/templates/unknown/paths", `{}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown template: got %d", w.Code)
+	}
+
+	var response apis.MatchResponse
+	w = serveTask(router, http.MethodGet, "/v1/templates/"+template.ID+"/matches", "")
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || len(response.Candidates) != 2 {
+		t.Fatalf("unexpected matches %d %+v", w.Code, response)
+	}
+	// 两台服务器其余得分相同，成功历史 +10，失败历史 -10
+	first, second := response.Candidates[0], response.Candidates[1]
+	if first.ComputeIDs[0] != good || first.Score != 110 || second.Score != 90 {
+		t.Fatalf("history not applied: %+v", response.Candidates)
+	}
+}
+
+func TestTaskTemplatesScopedToOwner(t *testing.T) {
+	withLimits(t, nil)
+	EnableTenancy(&ClientsConfig{Clients: map[string]ClientConfig{
+		"ops":       {Role: ClientRoleAdmin},
+		"cluster-a": {Enterprises: []string{"20001"}},
+		"cluster-b": {Enterprises: []string{"20002"}},
+	}})
+	defer EnableTenancy(nil)
+	router := taskRouter()
+
+	serve := func(cn, method, url, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.TLS = withClientCert(cn)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("cluster-a", http.MethodPost, "/v1/templates", `{"requirements":{"chip_type":"gpu"}}`)
+	var template apis.TaskTemplate
+	if w.Code != http.StatusCreated || json.NewDecoder(w.Body).Decode(&template) != nil || template.Owner != "cluster-a" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body)
+	}
+	own := matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001")
+	path := func(id string) string {
+		return `{"outcome":"succeeded","stages":[{"compute_ids":["` + id + `"],"outcome":"succeeded"}]}`
+	}
+
+	base := "/v1/templates/" + template.ID
+	for _, cn := range []string{"cluster-a", "ops"} {
+		for _, url := range []string{base, base + "/matches", base + "/paths"} {
+			if w := serve(cn, http.MethodGet, url, ""); w.Code != http.StatusOK {
+				t.Errorf("%s GET %s: got %d, want 200", cn, url, w.Code)
+			}
+		}
+	}
+	if w := serve("cluster-a", http.MethodPost, base+"/paths", path(own)); w.Code != http.StatusCreated {
+		t.Fatalf("owner could not report a path: %d %s", w.Code, w.Body)
+	}
+
+	// 其他租户的模板按不存在处理
+	for _, url := range []string{base, base + "/matches", base + "/paths"} {
+		if w := serve("cluster-b", http.MethodGet, url, ""); w.Code != http.StatusNotFound {
+			t.Errorf("cluster-b GET %s: got %d, want 404", url, w.Code)
+		}
+	}
+	if w := serve("cluster-b", http.MethodPost, base+"/paths", path(own)); w.Code != http.StatusNotFound {
+		t.Errorf("cluster-b reported a path on another tenant's template: %d", w.Code)
+	}
+
+	// 任务路径中的算力标识须属于本租户
+	other := "1101tc20002" + own[11:]
+	if w := serve("cluster-a", http.MethodPost, base+"/paths", path(other)); w.Code != http.StatusForbidden {
+		t.Errorf("path with another tenant's compute id: got %d, want 403", w.Code)
+	}
+	var paths []apis.TaskPath
+	if w := serve("ops", http.MethodGet, base+"/paths", ""); json.NewDecoder(w.Body).Decode(&paths) != nil || len(paths) != 1 {
+		t.Fatalf("expected 1 path, got %+v", paths)
+	}
+}
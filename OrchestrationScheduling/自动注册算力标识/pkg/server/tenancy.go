@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"register-power-resources/pkg/apis"
+)
+
+// ClientRoleAdmin 为可查看及修改全部租户资源的客户端角色
+const ClientRoleAdmin = "admin"
+
+// tenancy 为租户隔离使用的客户端配置，为 nil 时不做隔离
+var tenancy *ClientsConfig
+
+// EnableTenancy 开启租户隔离：客户端（已校验的证书 CN）只能查看及修改 Enterprises 中企业编码的算力标识，
+// admin 角色不受限制，未配置的客户端无权访问任何资源
+func EnableTenancy(config *ClientsConfig) {
+	tenancy = config
+}
+
+// tenantScope 为调用方可访问的资源范围，按算力标识中的企业编码划分
+type tenantScope struct {
+	all         bool
+	enterprises []string
+}
+
+var allTenants = tenantScope{all: true}
+
+func requestScope(r *http.Request) tenantScope {
+	if tenancy == nil {
+		return allTenants
+	}
+
+	client := tenancy.Clients[callerIdentity(r)]
+	if client.Role == ClientRoleAdmin {
+		return allTenants
+	}
+	enterprises := append([]string{}, client.Enterprises...)
+	sort.Strings(enterprises)
+	return tenantScope{enterprises: enterprises}
+}
+
+func (s tenantScope) allowsEnterprise(enterprise string) bool {
+	if s.all {
+		return true
+	}
+	i := sort.SearchStrings(s.enterprises, enterprise)
+	return i < len(s.enterprises) && s.enterprises[i] == enterprise
+}
+
+// allows 按算力标识本身的企业编码判断，不依赖资源是否已注册，避免泄露其他租户的资源是否存在
+func (s tenantScope) allows(id string) bool {
+	if s.all {
+		return true
+	}
+	if len(id) < 11 {
+		return false
+	}
+	return s.allowsEnterprise(id[6:11])
+}
+
+// forbidden 返回第一个不在范围内的算力标识
+func (s tenantScope) forbidden(ids []string) (string, bool) {
+	for _, id := range ids {
+		if !s.allows(id) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// selectResources 在调用方范围内按选择器查询。
+// 客户端属于多个企业时分别查询后按标识归并，每个企业最多取 offset+limit 条。
+func (s tenantScope) selectResources(selector apis.Selector, offset, limit int) ([]*apis.NodeResourceInfo, int) {
+	if s.all {
+		return registry.Select(selector, offset, limit)
+	}
+
+	enterprise, ok := selector.Fields[apis.FieldCompany]
+	if !ok && len(selector.Prefix) >= 11 {
+		enterprise, ok = selector.Prefix[6:11], true
+	}
+	if ok {
+		if !s.allowsEnterprise(enterprise) {
+			return []*apis.NodeResourceInfo{}, 0
+		}
+		return registry.Select(selector, offset, limit)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	window := 0
+	if limit > 0 {
+		window = offset + limit
+	}
+
+	var merged []*apis.NodeResourceInfo
+	total := 0
+	for _, enterprise := range s.enterprises {
+		scoped := apis.Selector{Prefix: selector.Prefix, Fields: map[string]string{apis.FieldCompany: enterprise}}
+		for field, value := range selector.Fields {
+			scoped.Fields[field] = value
+		}
+		resources, n := registry.Select(scoped, 0, window)
+		merged = append(merged, resources...)
+		total += n
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ID < merged[j].ID })
+
+	if offset > len(merged) {
+		offset = len(merged)
+	}
+	merged = merged[offset:]
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, total
+}
+
+func writeForbidden(w http.ResponseWriter, id string) {
+	requestErrors.Inc("tenant")
+	http.Error(w, fmt.Sprintf("compute id %s belongs to an enterprise outside the caller's tenant", id),
+		http.StatusForbidden)
+}
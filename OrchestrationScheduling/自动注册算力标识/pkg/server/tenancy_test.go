@@ -0,0 +1,55 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"testing"
+)
+
+// withClientCert 模拟已校验的客户端证书
+func withClientCert(cn string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
+
+func TestRequestScopeUsesVerifiedIdentity(t *testing.T) {
+	EnableTenancy(&ClientsConfig{Clients: map[string]ClientConfig{
+		"ops":       {Role: ClientRoleAdmin},
+		"cluster-a": {Enterprises: []string{"20001"}},
+	}})
+	defer EnableTenancy(nil)
+
+	// 请求头不作为身份，伪造 admin 仍为 anonymous
+	forged := httptest.NewRequest("GET", "/v1/resources", nil)
+	forged.Header.Set("X-Client-ID", "ops")
+	if scope := requestScope(forged); scope.all || len(scope.enterprises) != 0 {
+		t.Fatalf("forged header granted scope %+v", scope)
+	}
+
+	admin := httptest.NewRequest("GET", "/v1/resources", nil)
+	admin.TLS = withClientCert("ops")
+	if scope := requestScope(admin); !scope.all {
+		t.Fatalf("admin certificate not granted full scope: %+v", scope)
+	}
+
+	tenant := httptest.NewRequest("GET", "/v1/resources", nil)
+	tenant.TLS = withClientCert("cluster-a")
+	if scope := requestScope(tenant); scope.all || !scope.allowsEnterprise("20001") || scope.allowsEnterprise("20002") {
+		t.Fatalf("unexpected tenant scope %+v", scope)
+	}
+}
+
+func TestTenantIsolationRequiresClientCert(t *testing.T) {
+	o := NewOptions()
+	o.TenantIsolation, o.ClientsConfigFile = true, "clients.json"
+	if err := o.Validate(); err == nil {
+		t.Fatal("tenant isolation accepted without client certificates")
+	}
+
+	o.TLSCertFile, o.TLSKeyFile, o.ClientCAFile, o.RequireClientCert = "tls.crt", "tls.key", "ca.crt", true
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+// watchHistorySize 为保留的最近变更事件数，watch 的起点早于最早保留的事件时返回 410
+const watchHistorySize = 10000
+
+const (
+	defaultWatchTimeout = 30 * time.Second
+	// defaultMaxWatchTimeout 为未设置写超时时长轮询的最长等待时间
+	defaultMaxWatchTimeout = 50 * time.Second
+)
+
+var watchActions = map[string]string{
+	AuditActionRegister:   apis.WatchEventAdded,
+	AuditActionUpdate:     apis.WatchEventUpdated,
+	AuditActionUnregister: apis.WatchEventDeleted,
+	AuditActionExpire:     apis.WatchEventDeleted,
+}
+
+// watchHub 按变更序号保存最近的注册表变更，并在有新变更时唤醒等待中的 watch 请求
+type watchHub struct {
+	mutex    sync.Mutex
+	revision int64
+	events   []apis.WatchEvent
+	notify   chan struct{}
+}
+
+var watchEvents = &watchHub{notify: make(chan struct{})}
+
+func (h *watchHub) publish(eventType string, before, after *apis.NodeResourceInfo) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.revision++
+	event := apis.WatchEvent{Revision: h.revision, Type: eventType, Resource: after, Previous: before}
+	if eventType == apis.WatchEventDeleted {
+		event.Resource, event.Previous = before, nil
+	}
+	h.events = append(h.events, event)
+	if len(h.events) > watchHistorySize {
+		h.events = append([]apis.WatchEvent{}, h.events[len(h.events)-watchHistorySize:]...)
+	}
+
+	close(h.notify)
+	h.notify = make(chan struct{})
+}
+
+func (h *watchHub) currentRevision() int64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.revision
+}
+
+// since 返回序号大于 revision 的事件，以及无新事件时用于等待的通道；
+// revision 之后的事件已不再保留时 ok 为 false
+func (h *watchHub) since(revision int64) (events []apis.WatchEvent, current int64, wait <-chan struct{}, ok bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.events) > 0 && revision < h.events[0].Revision-1 {
+		return nil, h.revision, nil, false
+	}
+	for i := len(h.events) - 1; i >= 0 && h.events[i].Revision > revision; i-- {
+		events = append(events, h.events[i])
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, h.revision, h.notify, true
+}
+
+// recordChange 记录注册表变更的审计事件并通知 watch 请求
+func recordChange(r *http.Request, action string, before, after *apis.NodeResourceInfo) {
+	recordAudit(r, action, before, after)
+	watchEvents.publish(watchActions[action], before, after)
+}
+
+// WatchHandler 提供 /v1/watch
+type WatchHandler struct {
+	// maxTimeout 为长轮询的最长等待时间，保证在写超时前返回
+	maxTimeout time.Duration
+}
+
+// NewWatchHandler 按写超时的 3/4 限制长轮询的等待时间
+func NewWatchHandler(o *Options) *WatchHandler {
+	h := &WatchHandler{maxTimeout: defaultMaxWatchTimeout}
+	if o.WriteTimeout > 0 {
+		h.maxTimeout = o.WriteTimeout * 3 / 4
+	}
+	return h
+}
+
+// ServeHTTP 以长轮询方式返回 revision 之后的注册表变更，没有变更时最多等待 timeout。
+// 未指定 revision 时立即返回当前序号。
+func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("revision") == "" {
+		writeJSON(w, http.StatusOK, apis.WatchResponse{Revision: watchEvents.currentRevision(), Events: []apis.WatchEvent{}})
+		return
+	}
+	revision, err := strconv.ParseInt(query.Get("revision"), 10, 64)
+	if err != nil || revision < 0 {
+		http.Error(w, "invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	timeout := defaultWatchTimeout
+	if value := query.Get("timeout"); value != "" {
+		if timeout, err = time.ParseDuration(value); err != nil || timeout < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+	}
+	if timeout > h.maxTimeout {
+		timeout = h.maxTimeout
+	}
+
+	scope := requestScope(r)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		events, current, wait, ok := watchEvents.since(revision)
+		if !ok {
+			http.Error(w, "revision is too old, list resources and watch again", http.StatusGone)
+			return
+		}
+
+		scoped := []apis.WatchEvent{}
+		for _, event := range events {
+			if scope.allows(event.Resource.ID) {
+				scoped = append(scoped, event)
+			}
+		}
+		// 其他租户的变更同样推进序号，避免客户端重复等待同一批事件
+		if len(scoped) > 0 {
+			writeJSON(w, http.StatusOK, apis.WatchResponse{Revision: current, Events: scoped})
+			return
+		}
+		revision = current
+
+		select {
+		case <-wait:
+		case <-timer.C:
+			writeJSON(w, http.StatusOK, apis.WatchResponse{Revision: revision, Events: scoped})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
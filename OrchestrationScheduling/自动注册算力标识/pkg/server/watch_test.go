@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"register-power-resources/pkg/apis"
+)
+
+// withWatchHub 使用空的变更记录，测试结束后恢复
+func withWatchHub(t *testing.T) {
+	saved := watchEvents
+	watchEvents = &watchHub{notify: make(chan struct{})}
+	t.Cleanup(func() { watchEvents = saved })
+}
+
+func serveWatch(h *WatchHandler, query string) (int, apis.WatchResponse) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/watch?"+query, nil))
+	var response apis.WatchResponse
+	if w.Code == http.StatusOK {
+		json.NewDecoder(w.Body).Decode(&response)
+	}
+	return w.Code, response
+}
+
+func TestWatch(t *testing.T) {
+	withLimits(t, &ClientsConfig{})
+	withWatchHub(t)
+	h := NewWatchHandler(NewOptions())
+
+	a := matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001")
+	if code := registerRequest("", a); code != http.StatusCreated {
+		t.Fatalf("register returned %d", code)
+	}
+
+	// 未指定 revision 时返回当前序号
+	code, response := serveWatch(h, "")
+	if code != http.StatusOK || response.Revision != 1 || len(response.Events) != 0 {
+		t.Fatalf("unexpected response %d %+v", code, response)
+	}
+
+	code, response = serveWatch(h, "revision=0")
+	if code != http.StatusOK || response.Revision != 1 || len(response.Events) != 1 ||
+		response.Events[0].Type != apis.WatchEventAdded || response.Events[0].Resource.ID != a {
+		t.Fatalf("unexpected response %d %+v", code, response)
+	}
+
+	// 等待中的请求在有新变更时返回
+	done := make(chan apis.WatchResponse)
+	go func() {
+		_, response := serveWatch(h, "revision=1&timeout=10s")
+		done <- response
+	}()
+	time.Sleep(50 * time.Millisecond)
+	b := matchResourceID(1, 4, 2000, apis.NetworkTypeIB, "00001")
+	registerRequest("", b)
+	select {
+	case response = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not woken by a change")
+	}
+	if response.Revision != 2 || len(response.Events) != 1 || response.Events[0].Type != apis.WatchEventUpdated ||
+		response.Events[0].Resource.ID != b || response.Events[0].Previous.ID != a {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	for _, query := range []string{"revision=x", "revision=-1", "revision=0&timeout=x"} {
+		if code, _ := serveWatch(h, query); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, code)
+		}
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	withWatchHub(t)
+
+	// 等待时间不超过写超时的 3/4
+	options := NewOptions()
+	options.WriteTimeout = 100 * time.Millisecond
+	h := NewWatchHandler(options)
+	start := time.Now()
+	code, response := serveWatch(h, "revision=0&timeout=10s")
+	if code != http.StatusOK || len(response.Events) != 0 || response.Events == nil {
+		t.Fatalf("unexpected response %d %+v", code, response)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("watch waited %v, longer than the write timeout allows", elapsed)
+	}
+}
+
+func TestWatchExpiredRevision(t *testing.T) {
+	withWatchHub(t)
+	h := NewWatchHandler(NewOptions())
+	resource := apis.ParseResourceInfo(matchResourceID(1, 8, 2000, apis.NetworkTypeIB, "00001"))
+	for i := 0; i < watchHistorySize+2; i++ {
+		watchEvents.publish(apis.WatchEventAdded, nil, resource)
+	}
+
+	if code, _ := serveWatch(h, "revision=0"); code != http.StatusGone {
+		t.Fatalf("expected 410 for an expired revision, got %d", code)
+	}
+	code, response := serveWatch(h, fmt.Sprintf("revision=%d", watchHistorySize+1))
+	if code != http.StatusOK || len(response.Events) != 1 || response.Revision != watchHistorySize+2 {
+		t.Fatalf("unexpected response %d, revision %d, %d events", code, response.Revision, len(response.Events))
+	}
+}